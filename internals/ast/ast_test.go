@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/gabivlj/candice/internals/ops"
@@ -28,3 +29,67 @@ func TestBinaryOperation_String(t *testing.T) {
 			`((3 * 4) + 3)`,
 	)
 }
+
+func TestInspect(t *testing.T) {
+	program := &Program{Statements: []Statement{
+		&FunctionDeclarationStatement{Block: &Block{Statements: []Statement{
+			&DeclarationStatement{Name: "x", Expression: &Call{
+				Left:       &Identifier{Name: "f"},
+				Parameters: []Expression{&Integer{Value: 1}, &Integer{Value: 2}},
+			}},
+			&IfStatement{
+				Condition: &Identifier{Name: "x"},
+				Block:     &Block{},
+				ElseIfs:   []*ConditionPlusBlock{{Condition: &Integer{Value: 3}, Block: &Block{}}},
+			},
+		}}},
+	}}
+
+	var visited []string
+	Inspect(program, func(node Node) bool {
+		switch node := node.(type) {
+		case *Identifier:
+			visited = append(visited, node.Name)
+		case *Integer:
+			visited = append(visited, node.String())
+		case *Call:
+			// the parameters of the call aren't visited
+			visited = append(visited, "call")
+			return false
+		}
+
+		return true
+	})
+
+	a.AssertEqual(strings.Join(visited, " "), "call x 3")
+}
+
+func TestRewrite(t *testing.T) {
+	expression := &BinaryOperation{
+		Operation: ops.Add,
+		Left:      &Integer{Value: 1},
+		Right:     &PrefixOperation{Operation: ops.Subtract, Right: &Integer{Value: 1}},
+	}
+
+	statement := Rewrite(&ReturnStatement{Expression: expression}, func(node Node) Node {
+		if integer, ok := node.(*Integer); ok && integer.Value == 1 {
+			return &Identifier{Name: "one"}
+		}
+
+		return node
+	})
+
+	a.AssertEqual(statement.String(), "return (one + -one);")
+
+	defer func() {
+		a.Assert(recover() != nil)
+	}()
+
+	Rewrite(&ReturnStatement{Expression: expression}, func(node Node) Node {
+		if _, ok := node.(*Identifier); ok {
+			return &BreakStatement{}
+		}
+
+		return node
+	})
+}
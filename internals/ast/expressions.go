@@ -303,6 +303,7 @@ func (a *ArrayLiteral) String() string {
 }
 
 type AnonymousFunction struct {
+	node.Location
 	Token             token.Token
 	FunctionType      *ctypes.Function
 	Block             *Block
@@ -359,3 +360,20 @@ func (e *ExpressionBlock) String() string {
 }
 func (e *ExpressionBlock) GetType() ctypes.Type  { return e.Type }
 func (e *ExpressionBlock) GetToken() token.Token { return e.Token }
+
+// BadExpression is an expression with a syntax error
+type BadExpression struct {
+	*node.Node
+}
+
+func (b *BadExpression) GetType() ctypes.Type {
+	return b.Node.Type
+}
+
+func (b *BadExpression) String() string { return "<bad expression>" }
+
+func (b *BadExpression) expressionNode() {}
+
+func (b *BadExpression) GetToken() token.Token {
+	return b.Token
+}
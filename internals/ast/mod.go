@@ -4,12 +4,15 @@ import (
 	"strings"
 
 	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/node"
 	"github.com/gabivlj/candice/internals/token"
 )
 
 type Node interface {
 	String() string
 	GetToken() token.Token
+	// Span returns the range of the source that the node covers
+	Span() token.Span
 }
 
 type Expression interface {
@@ -26,6 +29,8 @@ type Statement interface {
 type Program struct {
 	ID         string
 	Statements []Statement
+	// Comments are the comments of the source in order, they aren't part of the statements
+	Comments []token.Comment
 }
 
 func (p *Program) GetToken() token.Token {
@@ -36,7 +41,17 @@ func (p *Program) GetToken() token.Token {
 	return p.Statements[0].GetToken()
 }
 
+// Span returns the range from the first statement to the last one
+func (p *Program) Span() token.Span {
+	if len(p.Statements) == 0 {
+		return token.Span{}
+	}
+
+	return p.Statements[0].Span().To(p.Statements[len(p.Statements)-1].Span())
+}
+
 type ExpressionStatement struct {
+	node.Location
 	Token      token.Token
 	Expression Expression
 }
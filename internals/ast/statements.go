@@ -5,10 +5,12 @@ import (
 	"strings"
 
 	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/node"
 	"github.com/gabivlj/candice/internals/token"
 )
 
 type Block struct {
+	node.Location
 	Statements []Statement
 	Token      token.Token
 }
@@ -35,6 +37,7 @@ type MacroBlock struct {
 }
 
 type ConditionPlusBlock struct {
+	node.Location
 	Block     *Block
 	Condition Expression
 }
@@ -55,8 +58,11 @@ func (c *ConditionPlusBlock) String() string {
 }
 
 type StructStatement struct {
+	node.Location
 	Token token.Token
 	Type  *ctypes.Struct
+	// Doc is the text of the /// comments before the struct
+	Doc string
 }
 
 func (s *StructStatement) GetToken() token.Token {
@@ -70,8 +76,11 @@ func (s *StructStatement) String() string {
 }
 
 type UnionStatement struct {
+	node.Location
 	Token token.Token
 	Type  *ctypes.Union
+	// Doc is the text of the /// comments before the union
+	Doc string
 }
 
 func (s *UnionStatement) GetToken() token.Token {
@@ -85,6 +94,7 @@ func (s *UnionStatement) String() string {
 }
 
 type DeclarationStatement struct {
+	node.Location
 	Token      token.Token
 	Name       string
 	Type       ctypes.Type
@@ -103,6 +113,7 @@ func (d *DeclarationStatement) String() string {
 }
 
 type MultipleDeclarationStatement struct {
+	node.Location
 	Token      token.Token
 	Names      []string
 	Type       ctypes.Type
@@ -125,6 +136,7 @@ func (m *MultipleDeclarationStatement) String() string {
 }
 
 type AssignmentStatement struct {
+	node.Location
 	Token      token.Token
 	Left       Expression
 	Expression Expression
@@ -139,6 +151,7 @@ func (d *AssignmentStatement) String() string {
 func (d *AssignmentStatement) GetToken() token.Token { return d.Token }
 
 type ForStatement struct {
+	node.Location
 	Token                token.Token
 	Condition            Expression
 	InitializerStatement Statement
@@ -178,6 +191,7 @@ func (f *ForStatement) String() string {
 }
 
 type IfStatement struct {
+	node.Location
 	Token     token.Token
 	Condition Expression
 	Block     *Block
@@ -217,9 +231,12 @@ func (i *IfStatement) String() string {
 }
 
 type FunctionDeclarationStatement struct {
+	node.Location
 	Token        token.Token
 	FunctionType *ctypes.Function
 	Block        *Block
+	// Doc is the text of the /// comments before the function
+	Doc string
 }
 
 func (f *FunctionDeclarationStatement) GetFunctionType() *ctypes.Function {
@@ -244,6 +261,7 @@ func (f *FunctionDeclarationStatement) String() string {
 func (f *FunctionDeclarationStatement) GetToken() token.Token { return f.Token }
 
 type ExternStatement struct {
+	node.Location
 	Token token.Token
 	Type  ctypes.Type
 }
@@ -257,6 +275,7 @@ func (e *ExternStatement) statementNode() {}
 func (e *ExternStatement) GetToken() token.Token { return e.Token }
 
 type ReturnStatement struct {
+	node.Location
 	Type       ctypes.Type
 	Token      token.Token
 	Expression Expression
@@ -275,6 +294,7 @@ func (r *ReturnStatement) statementNode() {}
 func (r *ReturnStatement) GetToken() token.Token { return r.Token }
 
 type ImportStatement struct {
+	node.Location
 	Token token.Token
 	Name  string
 	Types []ctypes.Type
@@ -294,6 +314,7 @@ func (i *ImportStatement) String() string {
 func (i *ImportStatement) GetToken() token.Token { return i.Token }
 
 type BreakStatement struct {
+	node.Location
 	Token token.Token
 }
 
@@ -304,6 +325,7 @@ func (b *BreakStatement) String() string { return b.Token.Literal }
 func (b *BreakStatement) GetToken() token.Token { return b.Token }
 
 type ContinueStatement struct {
+	node.Location
 	Token token.Token
 }
 
@@ -313,6 +335,7 @@ func (c *ContinueStatement) String() string        { return c.Token.Literal }
 func (c *ContinueStatement) GetToken() token.Token { return c.Token }
 
 type GenericTypeDefinition struct {
+	node.Location
 	Token        token.Token
 	Name         string
 	ReplacedType ctypes.Type
@@ -324,6 +347,7 @@ func (g *GenericTypeDefinition) String() string        { return g.Name }
 func (g *GenericTypeDefinition) GetToken() token.Token { return g.Token }
 
 type TypeDefinition struct {
+	node.Location
 	Token token.Token
 	Name  string
 	Type  ctypes.Type
@@ -336,6 +360,7 @@ func (g *TypeDefinition) String() string { return fmt.Sprintf("type %s = %s", g.
 func (g *TypeDefinition) GetToken() token.Token { return g.Token }
 
 type CaseStatement struct {
+	node.Location
 	Token token.Token
 	Case  Expression
 	Block *Block
@@ -354,7 +379,10 @@ func (c *CaseStatement) String() string {
 	return str.String()
 }
 
+func (c *CaseStatement) GetToken() token.Token { return c.Token }
+
 type SwitchStatement struct {
+	node.Location
 	Token     token.Token
 	Condition Expression
 	Cases     []*CaseStatement
@@ -385,3 +413,17 @@ func (s *SwitchStatement) String() string {
 }
 
 func (s *SwitchStatement) GetToken() token.Token { return s.Token }
+
+// BadStatement is a statement with a syntax error, the parser skips its tokens from Token
+// until the start of the next statement so it can keep parsing the rest of the program.
+type BadStatement struct {
+	node.Location
+	Token token.Token
+	// To is the token where the next statement starts
+	To token.Token
+}
+
+func (b *BadStatement) statementNode() {}
+
+func (b *BadStatement) String() string        { return "<bad statement>" }
+func (b *BadStatement) GetToken() token.Token { return b.Token }
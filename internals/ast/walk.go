@@ -0,0 +1,299 @@
+package ast
+
+import "fmt"
+
+// Visitor has the Visit method that Walk calls for each node. If the visitor w that Visit returns
+// isn't nil, Walk visits the children of the node with w and calls w.Visit(nil) after them.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree of node in depth first order, it calls v.Visit(node) and
+// then walks the children of the node with the visitor that Visit returns.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	for _, child := range Children(node) {
+		Walk(v, child)
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverses the tree of node in depth first order calling f for each node,
+// if f returns false the children of the node aren't visited. After the children
+// of a node f is called with nil.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Children returns the statements and expressions that are directly inside node in source order,
+// the fields that are nil aren't returned
+func Children(node Node) []Node {
+	var children []Node
+	addExpression := func(expression Expression) {
+		if expression != nil {
+			children = append(children, expression)
+		}
+	}
+
+	addStatement := func(statement Statement) {
+		if statement != nil {
+			children = append(children, statement)
+		}
+	}
+
+	addBlock := func(block *Block) {
+		if block != nil {
+			children = append(children, block)
+		}
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, statement := range n.Statements {
+			addStatement(statement)
+		}
+	case *BinaryOperation:
+		addExpression(n.Left)
+		addExpression(n.Right)
+	case *PrefixOperation:
+		addExpression(n.Right)
+	case *IndexAccess:
+		addExpression(n.Left)
+		addExpression(n.Access)
+	case *BuiltinCall:
+		for _, parameter := range n.Parameters {
+			addExpression(parameter)
+		}
+	case *Call:
+		addExpression(n.Left)
+		for _, parameter := range n.Parameters {
+			addExpression(parameter)
+		}
+	case *StructLiteral:
+		for _, value := range n.Values {
+			addExpression(value.Expression)
+		}
+	case *ArrayLiteral:
+		for _, value := range n.Values {
+			addExpression(value)
+		}
+	case *AnonymousFunction:
+		addBlock(n.Block)
+	case *CommaExpressions:
+		for _, expression := range n.Expressions {
+			addExpression(expression)
+		}
+	case *ExpressionBlock:
+		addBlock(n.Block)
+	case *ExpressionStatement:
+		addExpression(n.Expression)
+	case *Block:
+		for _, statement := range n.Statements {
+			addStatement(statement)
+		}
+	case *MacroBlock:
+		addBlock(n.Block)
+	case *ConditionPlusBlock:
+		addExpression(n.Condition)
+		addBlock(n.Block)
+	case *DeclarationStatement:
+		addExpression(n.Expression)
+	case *MultipleDeclarationStatement:
+		addExpression(n.Expression)
+	case *AssignmentStatement:
+		addExpression(n.Left)
+		addExpression(n.Expression)
+	case *ForStatement:
+		addStatement(n.InitializerStatement)
+		addExpression(n.Condition)
+		addStatement(n.Operation)
+		addBlock(n.Block)
+	case *IfStatement:
+		addExpression(n.Condition)
+		addBlock(n.Block)
+		for _, elseIf := range n.ElseIfs {
+			children = append(children, elseIf)
+		}
+
+		addBlock(n.Else)
+	case *FunctionDeclarationStatement:
+		addBlock(n.Block)
+	case *ReturnStatement:
+		addExpression(n.Expression)
+	case *ImportStatement:
+		if n.Path != nil {
+			children = append(children, n.Path)
+		}
+	case *CaseStatement:
+		addExpression(n.Case)
+		addBlock(n.Block)
+	case *SwitchStatement:
+		addExpression(n.Condition)
+		for _, c := range n.Cases {
+			children = append(children, c)
+		}
+
+		addBlock(n.Default)
+	case *Identifier, *Integer, *Float, *StringLiteral, *BadExpression,
+		*StructStatement, *UnionStatement, *ExternStatement, *BreakStatement, *ContinueStatement,
+		*GenericTypeDefinition, *TypeDefinition, *BadStatement:
+		// they don't have children
+	default:
+		panic(fmt.Sprintf("ast.Children: unexpected node %T", node))
+	}
+
+	return children
+}
+
+// Rewrite replaces each node of the tree of node with the node that f returns and returns the new root.
+// The children of a node are rewritten before it, so f receives them already replaced. f can return
+// the same node to keep it, otherwise it has to return a node that can take its place: an Expression
+// for an expression, a Statement for a statement, or a node of the same type for blocks, else ifs,
+// cases and import paths.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		rewriteStatements(n.Statements, f)
+	case *BinaryOperation:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Right = rewriteExpression(n.Right, f)
+	case *PrefixOperation:
+		n.Right = rewriteExpression(n.Right, f)
+	case *IndexAccess:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Access = rewriteExpression(n.Access, f)
+	case *BuiltinCall:
+		rewriteExpressions(n.Parameters, f)
+	case *Call:
+		n.Left = rewriteExpression(n.Left, f)
+		rewriteExpressions(n.Parameters, f)
+	case *StructLiteral:
+		for i := range n.Values {
+			n.Values[i].Expression = rewriteExpression(n.Values[i].Expression, f)
+		}
+	case *ArrayLiteral:
+		rewriteExpressions(n.Values, f)
+	case *AnonymousFunction:
+		n.Block = rewriteBlock(n.Block, f)
+	case *CommaExpressions:
+		rewriteExpressions(n.Expressions, f)
+	case *ExpressionBlock:
+		n.Block = rewriteBlock(n.Block, f)
+	case *ExpressionStatement:
+		n.Expression = rewriteExpression(n.Expression, f)
+	case *Block:
+		rewriteStatements(n.Statements, f)
+	case *MacroBlock:
+		n.Block = rewriteBlock(n.Block, f)
+	case *ConditionPlusBlock:
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Block = rewriteBlock(n.Block, f)
+	case *DeclarationStatement:
+		n.Expression = rewriteExpression(n.Expression, f)
+	case *MultipleDeclarationStatement:
+		n.Expression = rewriteExpression(n.Expression, f)
+	case *AssignmentStatement:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Expression = rewriteExpression(n.Expression, f)
+	case *ForStatement:
+		n.InitializerStatement = rewriteStatement(n.InitializerStatement, f)
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Operation = rewriteStatement(n.Operation, f)
+		n.Block = rewriteBlock(n.Block, f)
+	case *IfStatement:
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Block = rewriteBlock(n.Block, f)
+		for i, elseIf := range n.ElseIfs {
+			n.ElseIfs[i] = rewrite(elseIf, f)
+		}
+
+		n.Else = rewriteBlock(n.Else, f)
+	case *FunctionDeclarationStatement:
+		n.Block = rewriteBlock(n.Block, f)
+	case *ReturnStatement:
+		n.Expression = rewriteExpression(n.Expression, f)
+	case *ImportStatement:
+		if n.Path != nil {
+			n.Path = rewrite(n.Path, f)
+		}
+	case *CaseStatement:
+		n.Case = rewriteExpression(n.Case, f)
+		n.Block = rewriteBlock(n.Block, f)
+	case *SwitchStatement:
+		n.Condition = rewriteExpression(n.Condition, f)
+		for i, c := range n.Cases {
+			n.Cases[i] = rewrite(c, f)
+		}
+
+		n.Default = rewriteBlock(n.Default, f)
+	case *Identifier, *Integer, *Float, *StringLiteral, *BadExpression,
+		*StructStatement, *UnionStatement, *ExternStatement, *BreakStatement, *ContinueStatement,
+		*GenericTypeDefinition, *TypeDefinition, *BadStatement:
+		// they don't have children
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node %T", node))
+	}
+
+	return f(node)
+}
+
+// rewrite rewrites node and checks that the result can take its place
+func rewrite[T Node](node T, f func(Node) Node) T {
+	result := Rewrite(node, f)
+	rewritten, ok := result.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: %T can't be replaced with %T", node, result))
+	}
+
+	return rewritten
+}
+
+func rewriteExpression(expression Expression, f func(Node) Node) Expression {
+	if expression == nil {
+		return nil
+	}
+
+	return rewrite(expression, f)
+}
+
+func rewriteStatement(statement Statement, f func(Node) Node) Statement {
+	if statement == nil {
+		return nil
+	}
+
+	return rewrite(statement, f)
+}
+
+func rewriteBlock(block *Block, f func(Node) Node) *Block {
+	if block == nil {
+		return nil
+	}
+
+	return rewrite(block, f)
+}
+
+func rewriteExpressions(expressions []Expression, f func(Node) Node) {
+	for i, expression := range expressions {
+		expressions[i] = rewriteExpression(expression, f)
+	}
+}
+
+func rewriteStatements(statements []Statement, f func(Node) Node) {
+	for i, statement := range statements {
+		statements[i] = rewriteStatement(statement, f)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"sort"
 	"strconv"
 	"time"
 
 	paths "path"
 
 	"github.com/gabivlj/candice/internals/compiler"
+	"github.com/gabivlj/candice/internals/eval"
 	"github.com/gabivlj/candice/internals/lexer"
 	"github.com/gabivlj/candice/internals/parser"
 	"github.com/gabivlj/candice/internals/semantic"
@@ -34,74 +36,173 @@ func ExecuteProject() {
 	if err != nil {
 		logger.Error("Flags", "Error retrieving flags: "+err.Error(), `
 	Usage:
-		candice <mode> <path> <flags>
+		candice <mode> <path> <flags> [-- <program arguments>]
 	Modes:
 		run - Run the project in the desired path.
 		build - Creates an executable of the project in the desired path.
 		init - Creates a candice project
-		tree - Showcases an AST of the file in the terminal
+		tree - Showcases an AST of the file or of every module of the project in the terminal
+		std path - Prints the directory where the standard library is located
 	Flags:
 		--release - Create or runs an optimized build of the project (run, build).
+		--no-cache - Don't reuse nor store compiled modules in the build cache (run, build).
+		--watch - Rebuild (and rerun) the project every time one of its files changes (run, build).
+		-D NAME=value - Defines a symbol for #if, overriding the defines of candice.json (run, build, tree).
+		--format text|json|dot - Output format of the AST, json and dot are meant for tools and Graphviz (tree).
+		--diagnostics-format text|json|sarif - Write errors and warnings to stderr as a json or SARIF document.
 		`)
 		return
 	}
 
+	if err := logger.SetFormat(flags.DiagnosticsFormat); err != nil {
+		logger.Error("Flags", err.Error())
+		os.Exit(1)
+	}
+
+	// the diagnostics of json and sarif formats are written when candice finishes
+	defer logger.Flush()
+
 	if flags.Mode == "init" {
 		createSampleProject(flags.Path)
 		return
 	}
 
-	if flags.Mode == "tree" {
-		bytes, err := os.ReadFile(flags.Path)
-		if err != nil {
-			logger.Error("File", err.Error())
-			return
+	if flags.Mode == "std" {
+		if flags.Path != "path" {
+			logger.Error("Flags", "unknown std command "+flags.Path, "Usage: candice std path")
+			exit(1)
 		}
 
-		if err := tree_printer.WriteOutput(string(bytes), os.Stdout); err != nil {
-			logger.Error("Generating AST", err.Error())
+		directory := locateStd()
+		if directory == "" {
+			logger.Error("Standard Library", "couldn't find the standard library, set "+StdEnvironmentVariable+" to its directory")
+			exit(1)
 		}
+
+		fmt.Println(directory)
 		return
 	}
 
-	config, err := ParseConfigurationFile(paths.Join(flags.Path, "candice.json"))
+	if flags.Mode == "tree" {
+		if !printTree(flags) {
+			exit(1)
+		}
+
+		return
+	}
+
+	if flags.Watch {
+		watch(flags)
+		return
+	}
+
+	config, _, ok := buildProject(flags)
+	if !ok {
+		exit(1)
+	}
+
+	// write the diagnostics of the build before the program starts writing its own output
+	logger.Flush()
+
+	if flags.Mode == "run" {
+		cmd, ok := startProgram(config, flags.ProgramArguments)
+		if !ok {
+			exit(1)
+		}
+
+		code, description := exitStatus(cmd.Wait())
+		if description != "" {
+			logger.Error("Running", description)
+		}
+
+		exit(code)
+	} else {
+		passedTime := float64(time.Now().UnixMilli() - current.UnixMilli())
+		logger.Success("BUILD SUCCESSFUL. (" + strconv.FormatFloat(passedTime/1000, 'f', 3, 64) + "s)")
+	}
+}
+
+// analyzeProject parses and analyzes the project on flags.Path, it returns the configuration of the project,
+// the files that are part of the project so far, the analyzed entrypoint and if the analysis was successful.
+// Errors are printed to the terminal.
+func analyzeProject(flags Flags) (ProjectConfiguration, []string, *semantic.Semantic, bool) {
+	configurationPath := paths.Join(flags.Path, "candice.json")
+	files := []string{configurationPath}
+	config, err := ParseConfigurationFile(configurationPath)
 
 	if err != nil {
 		logger.Error("Project", err.Error())
-		return
+		return config, files, nil, false
+	}
+
+	defines, err := config.DefinesWith(flags.Defines)
+	if err != nil {
+		logger.Error("Project", err.Error())
+		return config, files, nil, false
+	}
+
+	eval.SetDefines(defines)
+
+	dependencies, dependencyFlags, err := resolveDependencies(flags.Path, config)
+	if err != nil {
+		logger.Error("Dependencies", err.Error())
+		return config, files, nil, false
 	}
 
-	codeEntryPoint, err := os.ReadFile(config.EntryPoint)
+	config.CompilerFlags = append(config.CompilerFlags, dependencyFlags...)
+	// The entrypoint is relative to the project
+	entryPoint := paths.Join(flags.Path, config.EntryPoint)
+	files = append(files, entryPoint)
+	codeEntryPoint, err := os.ReadFile(entryPoint)
 
 	if err != nil {
 		logger.Error("Project", err.Error())
-		return
+		return config, files, nil, false
 	}
 
-	p := parser.New(lexer.New(string(codeEntryPoint)))
+	p := parser.New(lexer.NewFile(entryPoint, string(codeEntryPoint)))
+	p.ID = semantic.ModuleID(entryPoint, codeEntryPoint, "")
 	tree := p.Parse()
 	if len(p.Errors) > 0 {
 		for _, err := range p.Errors {
-			logger.Error("Parsing", err.Error())
+			logger.ReportError("Parsing", err)
 		}
-		return
+		return config, files, nil, false
 	}
 	s := semantic.New()
+	s.FilePath = entryPoint
+	s.Dependencies = dependencies
+	s.SearchPaths = []string{flags.Path}
+	s.StdDirectory = locateStd()
 	s.Analyze(tree)
+	files = append(files, s.Files()...)
 
 	for _, err := range s.Warnings {
-		logger.Warning(err.Error())
+		logger.ReportWarning(err)
 	}
 
 	if len(s.Errors) > 0 {
 		for _, err := range s.Errors {
-			logger.Error("Analyzing", err.Error())
+			logger.ReportError("Analyzing", err)
 		}
-		return
+		return config, files, nil, false
+	}
+
+	s.ComputeChecksum(codeEntryPoint, "")
+	return config, files, s, true
+}
+
+// buildProject compiles the project on flags.Path, it returns the configuration of the project, the files
+// that are part of the project so far and if the build was successful. Errors are printed
+// to the terminal.
+func buildProject(flags Flags) (ProjectConfiguration, []string, bool) {
+	config, files, s, ok := analyzeProject(flags)
+	if !ok {
+		return config, files, false
 	}
 
 	c := compiler.New(s)
-	c.CompileWithEventHandler(tree, func(e compiler.Event) {
+	c.CompileWithEventHandler(s.Root, func(e compiler.Event) {
 		if e.Kind == compiler.AddFlags {
 			config.CompilerFlags = append(config.CompilerFlags, e.Data)
 		}
@@ -116,43 +217,146 @@ func ExecuteProject() {
 		config.Output += ".o"
 	}
 
-	if config.CompileKind == PureLLVM {
+	if config.CompileKind != PureLLVM && config.CompileKind != CXX {
+		logger.Error("Configuration", "Unknown compiling kind, use either 'llvm' or 'cxx'.")
+		return config, files, false
+	}
+
+	if config.BinaryKind != Object && !flags.NoCache {
+		cache, err := NewCache(flags.Path)
+		if err != nil {
+			logger.Error("Build Cache", err.Error())
+			return config, files, false
+		}
+
+		if _, err := compileUnits(cache, c.Units(), config.Output, config.CXX, config.CompilerFlags); err != nil {
+			logger.Error("Internally At Compile Time", err.Error())
+			return config, files, false
+		}
+	} else if config.CompileKind == PureLLVM {
 		err := c.GenerateExecutableExperimental(config.Output, config.CXX, config.CompilerFlags, flags.Release, config.BinaryKind != Object)
 		if err != nil {
 			logger.Error("Internally At Compile Time", err.Error())
-			return
+			return config, files, false
 		}
-
-	} else if config.CompileKind == CXX {
+	} else {
 		err := c.GenerateExecutableCXX(config.Output, config.CXX, config.CompilerFlags)
 		if err != nil {
 			logger.Error("Internally At Compile Time", err.Error())
-			return
+			return config, files, false
 		}
+	}
 
-	} else {
-		logger.Error("Configuration", "Unknown compiling kind, use either 'llvm' or 'cxx'.")
-		return
+	return config, files, true
+}
+
+// printTree writes the AST of flags.Path to the terminal in the format of the flags. If the path is a project
+// its modules are analyzed first, so the tree has the resolved types, and every imported module is written too.
+func printTree(flags Flags) bool {
+	format, err := tree_printer.ParseFormat(flags.Format)
+	if err != nil {
+		logger.Error("Flags", err.Error())
+		return false
 	}
 
-	if flags.Mode == "run" {
-		if config.BinaryKind == Object {
-			logger.Error("you can't run a project that needs to an object!", "Consider setting 'binary' to 'exe' in candice.json")
-			return
+	if info, err := os.Stat(flags.Path); err == nil && info.IsDir() {
+		_, _, s, ok := analyzeProject(flags)
+		if !ok {
+			return false
 		}
 
-		cmd := exec.Command("./" + config.Output)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Stdin = os.Stdin
-		err := cmd.Run()
-		if err != nil {
-			fmt.Println(err)
+		if err := tree_printer.WriteModules(os.Stdout, format, projectModules(s)); err != nil {
+			logger.Error("Generating AST", err.Error())
+			return false
 		}
-	} else {
-		passedTime := float64(time.Now().UnixMilli() - current.UnixMilli())
-		logger.Success("BUILD SUCCESSFUL. (" + strconv.FormatFloat(passedTime/1000, 'f', 3, 64) + "s)")
+
+		return true
+	}
+
+	eval.SetDefines(flags.Defines)
+	bytes, err := os.ReadFile(flags.Path)
+	if err != nil {
+		logger.Error("File", err.Error())
+		return false
 	}
+
+	if format == tree_printer.Text {
+		if err := tree_printer.WriteOutput(string(bytes), os.Stdout); err != nil {
+			logger.ReportError("Generating AST", err)
+		}
+
+		return true
+	}
+
+	p := parser.New(lexer.NewFile(flags.Path, string(bytes)))
+	p.ID = semantic.ModuleID(flags.Path, bytes, "")
+	tree := p.Parse()
+	if len(p.Errors) > 0 {
+		for _, err := range p.Errors {
+			logger.ReportError("Parsing", err)
+		}
+
+		return false
+	}
+
+	module := tree_printer.Module{Path: flags.Path, ID: p.ID, Program: tree}
+	if err := tree_printer.WriteModules(os.Stdout, format, []tree_printer.Module{module}); err != nil {
+		logger.Error("Generating AST", err.Error())
+		return false
+	}
+
+	return true
+}
+
+// projectModules returns the entrypoint of the project followed by every module that it imports,
+// directly or through other modules, sorted by path. A module that is imported with different
+// generic types appears once for each of them.
+func projectModules(root *semantic.Semantic) []tree_printer.Module {
+	seen := map[*semantic.Semantic]bool{root: true}
+	var imported []*semantic.Semantic
+	var visit func(module *semantic.Semantic)
+	visit = func(module *semantic.Semantic) {
+		for _, dependency := range module.Imports() {
+			if seen[dependency] {
+				continue
+			}
+
+			seen[dependency] = true
+			imported = append(imported, dependency)
+			visit(dependency)
+		}
+	}
+
+	visit(root)
+	sort.SliceStable(imported, func(i, j int) bool {
+		return imported[i].FilePath < imported[j].FilePath
+	})
+
+	modules := make([]tree_printer.Module, 0, len(imported)+1)
+	for _, module := range append([]*semantic.Semantic{root}, imported...) {
+		modules = append(modules, tree_printer.Module{Path: module.FilePath, ID: module.Root.ID, Program: module.Root})
+	}
+
+	return modules
+}
+
+// startProgram starts the compiled program of the project attached to the terminal
+func startProgram(config ProjectConfiguration, arguments []string) (*exec.Cmd, bool) {
+	if config.BinaryKind == Object {
+		logger.Error("you can't run a project that needs to an object!", "Consider setting 'binary' to 'exe' in candice.json")
+		return nil, false
+	}
+
+	cmd := exec.Command("./"+config.Output, arguments...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	if err := cmd.Start(); err != nil {
+		logger.Error("Running", err.Error())
+		return nil, false
+	}
+
+	return cmd, true
 }
 
 func createSampleProject(basePath string) {
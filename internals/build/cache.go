@@ -0,0 +1,155 @@
+package build
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/gabivlj/candice/internals/compiler"
+	"github.com/gabivlj/candice/internals/eval"
+)
+
+// CacheDirectory is the directory inside the project where the build cache lives
+const CacheDirectory = ".candice/cache"
+
+// Cache is an on-disk cache of object files, one per compiled module.
+type Cache struct {
+	Directory string
+}
+
+func NewCache(projectPath string) (*Cache, error) {
+	directory := path.Join(projectPath, CacheDirectory)
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Cache{Directory: directory}, nil
+}
+
+// Key returns the cache key of a module unit. The checksum of the module already includes
+// its source code, its generic types and the checksums of its imports, flags, the symbols defined
+// for #if and the compiler version are added because they change the resulting object.
+func (c *Cache) Key(checksum string, cxx string, flags []string) string {
+	hash := sha256.New()
+	hash.Write([]byte(Version))
+	hash.Write([]byte{0})
+	hash.Write([]byte(checksum))
+	hash.Write([]byte{0})
+	hash.Write([]byte(cxx))
+	for _, flag := range flags {
+		hash.Write([]byte{0})
+		hash.Write([]byte(flag))
+	}
+
+	for _, define := range eval.DefinesKey() {
+		hash.Write([]byte{0})
+		hash.Write([]byte("-D" + define))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Object returns the path of the object file for that key and if it exists.
+func (c *Cache) Object(key string) (string, bool) {
+	objectPath := path.Join(c.Directory, key+".o")
+	_, err := os.Stat(objectPath)
+	return objectPath, err == nil
+}
+
+// isLinkFlag returns true for flags that only make sense when linking
+func isLinkFlag(flag string) bool {
+	return strings.HasPrefix(flag, "-l") || strings.HasPrefix(flag, "-L") || strings.HasPrefix(flag, "-Wl,")
+}
+
+// compileUnits compiles every module unit into an object file, reusing the objects of the cache
+// for modules that didn't change, and links them into output.
+// It returns how many modules were reused from the cache.
+func compileUnits(cache *Cache, units []compiler.Unit, output string, cxx string, flags []string) (int, error) {
+	compileFlags := []string{}
+	for _, flag := range flags {
+		if !isLinkFlag(flag) {
+			compileFlags = append(compileFlags, flag)
+		}
+	}
+
+	objects := make([]string, 0, len(units))
+	reused := 0
+
+	// Modules are independent from each other at this point, so we compile them in parallel
+	workers := make(chan struct{}, runtime.NumCPU())
+	errs := make([]error, len(units))
+	wg := sync.WaitGroup{}
+	for i, unit := range units {
+		key := cache.Key(unit.Module.Checksum, cxx, compileFlags)
+		objectPath, exists := cache.Object(key)
+		objects = append(objects, objectPath)
+		if exists && unit.Module.Checksum != "" {
+			reused++
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, unit compiler.Unit, objectPath string) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			errs[i] = compileUnit(unit, objectPath, cxx, compileFlags)
+		}(i, unit, objectPath)
+	}
+
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return reused, err
+		}
+	}
+
+	command := append(objects, "-o", output)
+	command = append(command, flags...)
+	return reused, runCommand(cxx, command)
+}
+
+func compileUnit(unit compiler.Unit, objectPath string, cxx string, flags []string) error {
+	intermediatePath := strings.TrimSuffix(objectPath, ".o") + ".ll"
+	defer os.Remove(intermediatePath)
+	fd, err := os.Create(intermediatePath)
+	if err != nil {
+		return err
+	}
+
+	_, err = unit.IR.WriteTo(fd)
+	fd.Close()
+	if err != nil {
+		return err
+	}
+
+	// Compile into a temporary file so a failed or interrupted compilation
+	// doesn't leave a corrupted object in the cache.
+	temporaryObject := objectPath + ".tmp"
+	command := append([]string{"-c", intermediatePath, "-o", temporaryObject}, flags...)
+	if err := runCommand(cxx, command); err != nil {
+		os.Remove(temporaryObject)
+		return err
+	}
+
+	return os.Rename(temporaryObject, objectPath)
+}
+
+func runCommand(name string, args []string) error {
+	cmd := exec.Command(name, args...)
+	output := bytes.Buffer{}
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return errors.New("error compiling " + name + " " + strings.Join(args, " ") + " :\n" + output.String())
+	}
+
+	return nil
+}
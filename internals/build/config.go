@@ -2,8 +2,10 @@ package build
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"strconv"
 )
 
 type CompileKind string
@@ -25,6 +27,67 @@ type ProjectConfiguration struct {
 	Output        string      `json:"output"`
 	CompilerFlags []string    `json:"flags"`
 	BinaryKind    BinaryKind  `json:"binary"`
+
+	// Dependencies maps the name of each dependency to where it is, like "sdl": { "path": "vendor/sdl" }
+	// or the shorthand "sdl": "vendor/sdl". Vendored dependencies are just directories inside of the project.
+	Dependencies map[string]Dependency `json:"dependencies"`
+
+	// Defines are the symbols that #if can use, like "defines": { "DEBUG": true, "LEVEL": 2 }.
+	// They can be overridden with -D NAME=value.
+	Defines map[string]interface{} `json:"defines"`
+}
+
+// Dependency is a dependency of a project
+type Dependency struct {
+	// Path is the directory of the dependency, relative to the project
+	Path string `json:"path"`
+}
+
+// UnmarshalJSON accepts a dependency as an object or as the string of its path
+func (d *Dependency) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		d.Path = path
+		return nil
+	}
+
+	type dependency Dependency
+	var object dependency
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("a dependency must be the string of its path or an object like { \"path\": \"vendor/name\" }")
+	}
+
+	*d = Dependency(object)
+	return nil
+}
+
+// DefinesWith returns the defines of the project as strings, with the ones in overrides
+// taking precedence. Booleans are written as true and false, so they are defined the same
+// way as -D NAME=true and -D NAME=false.
+func (p ProjectConfiguration) DefinesWith(overrides map[string]string) (map[string]string, error) {
+	defines := map[string]string{}
+	for name, value := range p.Defines {
+		switch value := value.(type) {
+		case bool:
+			defines[name] = strconv.FormatBool(value)
+		case float64:
+			if value != float64(int64(value)) {
+				return nil, fmt.Errorf("define %s must be an integer, got %v", name, value)
+			}
+
+			defines[name] = strconv.FormatInt(int64(value), 10)
+		case string:
+			defines[name] = value
+		default:
+			return nil, fmt.Errorf("define %s must be a boolean, an integer or a string", name)
+		}
+	}
+
+	for name, value := range overrides {
+		defines[name] = value
+	}
+
+	return defines, nil
 }
 
 func ParseConfiguration(reader io.Reader) (ProjectConfiguration, error) {
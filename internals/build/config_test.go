@@ -0,0 +1,165 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gabivlj/candice/internals/eval"
+	"github.com/gabivlj/candice/pkg/a"
+)
+
+func TestConfiguration_Dependencies(t *testing.T) {
+	directory := t.TempDir()
+	for _, dependency := range []string{"vendor/math", "sdl", "sdl/vendor/gl"} {
+		a.AssertErr(os.MkdirAll(filepath.Join(directory, dependency), 0o755))
+	}
+
+	// the dependencies of a dependency are relative to it
+	a.AssertErr(os.WriteFile(filepath.Join(directory, "sdl", "candice.json"),
+		[]byte(`{"flags": ["-lSDL2", "-L./lib", "-I", "include", "-I/usr/include/SDL2"], "dependencies": {"gl": {"path": "vendor/gl"}}}`), 0o644))
+
+	config, err := ParseConfiguration(strings.NewReader(`{
+		"name": "game",
+		"dependencies": { "math": "vendor/math", "sdl": { "path": "sdl" } }
+	}`))
+	a.AssertErr(err)
+	a.AssertEqual(config.Dependencies["math"].Path, "vendor/math")
+	a.AssertEqual(config.Dependencies["sdl"].Path, "sdl")
+
+	directories, flags, err := resolveDependencies(directory, config)
+	a.AssertErr(err)
+	a.AssertEqual(directories["math"], filepath.Join(directory, "vendor/math"))
+	a.AssertEqual(directories["sdl"], filepath.Join(directory, "sdl"))
+	a.AssertEqual(directories["gl"], filepath.Join(directory, "sdl/vendor/gl"))
+	// relative paths of the flags are relative to the dependency
+	sdl := filepath.Join(directory, "sdl")
+	a.AssertEqual(strings.Join(flags, " "), "-lSDL2 -L"+filepath.Join(sdl, "lib")+" -I "+filepath.Join(sdl, "include")+" -I/usr/include/SDL2")
+
+	for _, dependencies := range []string{`{"math": 1}`, `{"math": ["vendor/math"]}`, `{"math": {"path": 1}}`} {
+		_, err := ParseConfiguration(strings.NewReader(`{"dependencies": ` + dependencies + `}`))
+		a.Assert(err != nil, dependencies)
+	}
+
+	for _, dependencies := range []string{`{"math": {}}`, `{"math": "missing"}`} {
+		config, err := ParseConfiguration(strings.NewReader(`{"dependencies": ` + dependencies + `}`))
+		a.AssertErr(err)
+		_, _, err = resolveDependencies(directory, config)
+		a.Assert(err != nil, dependencies)
+	}
+}
+
+func TestConfiguration_Defines(t *testing.T) {
+	config, err := ParseConfiguration(strings.NewReader(`{
+		"defines": { "DEBUG": true, "RELEASE": false, "LEVEL": 2, "BACKEND": "gl" }
+	}`))
+	a.AssertErr(err)
+	defines, err := config.DefinesWith(map[string]string{"LEVEL": "3"})
+	a.AssertErr(err)
+	a.AssertEqual(defines["DEBUG"], "true")
+	a.AssertEqual(defines["RELEASE"], "false")
+	a.AssertEqual(defines["LEVEL"], "3")
+	a.AssertEqual(defines["BACKEND"], "gl")
+
+	for _, value := range []string{"1.5", "[1]", "{}", "null"} {
+		config, err := ParseConfiguration(strings.NewReader(`{"defines": {"X": ` + value + `}}`))
+		a.AssertErr(err)
+		_, err = config.DefinesWith(nil)
+		a.Assert(err != nil, value)
+	}
+}
+
+func TestCache_Key(t *testing.T) {
+	cache := &Cache{Directory: t.TempDir()}
+	defer eval.SetDefines(nil)
+	eval.SetDefines(map[string]string{"DEBUG": "true", "LEVEL": "2"})
+	key := cache.Key("checksum", "clang", []string{"-m64"})
+	a.AssertEqual(cache.Key("checksum", "clang", []string{"-m64"}), key)
+
+	a.Assert(cache.Key("other", "clang", []string{"-m64"}) != key, "the checksum changes the key")
+	a.Assert(cache.Key("checksum", "gcc", []string{"-m64"}) != key, "the compiler changes the key")
+	a.Assert(cache.Key("checksum", "clang", []string{"-m64", "-O2"}) != key, "the flags change the key")
+
+	// the defines are the same no matter where they come from
+	eval.SetDefines(map[string]string{"LEVEL": "0x2", "DEBUG": "1"})
+	a.AssertEqual(cache.Key("checksum", "clang", []string{"-m64"}), key)
+
+	eval.SetDefines(map[string]string{"DEBUG": "false", "LEVEL": "2"})
+	a.Assert(cache.Key("checksum", "clang", []string{"-m64"}) != key, "the defines change the key")
+}
+
+func TestCache_KeyOfGenericModules(t *testing.T) {
+	directory := t.TempDir()
+	a.AssertErr(os.WriteFile(filepath.Join(directory, "candice.json"), []byte(`{"name": "generic", "entrypoint": "main.cd"}`), 0o644))
+	a.AssertErr(os.WriteFile(filepath.Join(directory, "box.cd"), []byte(`
+type T
+
+struct Box {
+    value T
+}
+
+func Size() i32 {
+    return @sizeof(T)
+}
+`), 0o644))
+
+	checksumOfBox := func(point string) string {
+		a.AssertErr(os.WriteFile(filepath.Join(directory, "main.cd"), []byte(point+`
+import box, Point, "./box.cd"
+
+func main() {
+    b := @box.Box{}
+    @print(box.Size())
+}
+`), 0o644))
+
+		_, _, s, ok := analyzeProject(Flags{Path: directory})
+		a.Assert(ok, "the project should be analyzed")
+		imports := s.Imports()
+		a.Assert(len(imports) == 1, imports)
+		return imports[0].Checksum
+	}
+
+	checksum := checksumOfBox("struct Point { x i32 \n y i32 }")
+	a.AssertEqual(checksumOfBox("struct Point { x i32 \n y i32 }"), checksum)
+	a.Assert(checksumOfBox("struct Point { x i64 \n y i64 }") != checksum, "the fields of a generic type change the module")
+	a.Assert(checksumOfBox("struct Point { x i32 \n y i32 \n z i32 }") != checksum, "the fields of a generic type change the module")
+}
+
+func TestConfiguration_DefinesOverride(t *testing.T) {
+	config, err := ParseConfiguration(strings.NewReader(`{
+		"name": "defines",
+		"defines": { "DEBUG": true, "TRACE": false, "LEVEL": 1, "BACKEND": "gl" }
+	}`))
+	a.AssertErr(err)
+
+	args := os.Args
+	defer func() { os.Args = args }()
+	os.Args = []string{"candice", "run", ".", "-D", "DEBUG=false", "-DTRACE=true", "-D", "LEVEL=3", "-D", "VERBOSE"}
+	flags, err := retrieveFlags()
+	a.AssertErr(err)
+
+	defines, err := config.DefinesWith(flags.Defines)
+	a.AssertErr(err)
+	eval.SetDefines(defines)
+	defer eval.SetDefines(nil)
+
+	expected := map[string]int64{"DEBUG": 0, "TRACE": 1, "LEVEL": 3, "VERBOSE": 1}
+	for name, value := range expected {
+		symbol, ok := eval.Symbol(name)
+		integer, isInteger := symbol.(*eval.Integer)
+		a.Assert(ok && isInteger && integer.Value == value, name, symbol)
+	}
+
+	backend, _ := eval.Symbol("BACKEND")
+	a.AssertEqual(backend.(*eval.String).Value, "gl")
+
+	// candice.json and -D define booleans the same way
+	defines, err = config.DefinesWith(nil)
+	a.AssertErr(err)
+	eval.SetDefines(defines)
+	debug, _ := eval.Symbol("DEBUG")
+	trace, _ := eval.Symbol("TRACE")
+	a.Assert(debug.IsTruthy() && !trace.IsTruthy(), debug, trace)
+}
@@ -0,0 +1,111 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// resolveDependencies returns the absolute directory of every dependency of the project by name,
+// including the dependencies of its dependencies, and the compiler flags that their candice.json
+// files declare, so they can be passed to the link step.
+func resolveDependencies(projectPath string, config ProjectConfiguration) (map[string]string, []string, error) {
+	directories := map[string]string{}
+	flags := []string{}
+	err := collectDependencies(projectPath, config, directories, &flags)
+	return directories, flags, err
+}
+
+func collectDependencies(projectPath string, config ProjectConfiguration, directories map[string]string, flags *[]string) error {
+	// Sorted so the flags are always in the same order
+	names := make([]string, 0, len(config.Dependencies))
+	for name := range config.Dependencies {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		dependencyPath := config.Dependencies[name].Path
+		if dependencyPath == "" {
+			return fmt.Errorf("dependency %s doesn't have a path", name)
+		}
+
+		if !filepath.IsAbs(dependencyPath) {
+			dependencyPath = filepath.Join(projectPath, dependencyPath)
+		}
+
+		directory, err := filepath.Abs(dependencyPath)
+		if err != nil {
+			return fmt.Errorf("dependency %s: %w", name, err)
+		}
+
+		if existing, ok := directories[name]; ok {
+			if existing != directory {
+				return fmt.Errorf("dependency %s is declared with two different directories: %s and %s", name, existing, directory)
+			}
+
+			continue
+		}
+
+		if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+			return fmt.Errorf("dependency %s: directory %s doesn't exist", name, directory)
+		}
+
+		directories[name] = directory
+		dependencyConfig, err := ParseConfigurationFile(filepath.Join(directory, "candice.json"))
+		if os.IsNotExist(err) {
+			// Dependencies without a candice.json are just a directory of candice files
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("dependency %s: error parsing candice.json: %w", name, err)
+		}
+
+		*flags = append(*flags, rebaseFlags(directory, dependencyConfig.CompilerFlags)...)
+		if err := collectDependencies(directory, dependencyConfig, directories, flags); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pathFlags are the flags whose value is a path, written joined (-Llib) or as the next argument (-L lib)
+var pathFlags = []string{"-L", "-I"}
+
+// rebaseFlags makes the relative paths of the flags of a dependency relative to its directory,
+// they are written from the point of view of the dependency but used by the project that imports it.
+func rebaseFlags(directory string, flags []string) []string {
+	rebased := make([]string, 0, len(flags))
+	for i := 0; i < len(flags); i++ {
+		flag := flags[i]
+		for _, prefix := range pathFlags {
+			if flag == prefix && i+1 < len(flags) {
+				rebased = append(rebased, flag)
+				i++
+				flag = rebasePath(directory, flags[i])
+				break
+			}
+
+			if strings.HasPrefix(flag, prefix) && len(flag) > len(prefix) {
+				flag = prefix + rebasePath(directory, flag[len(prefix):])
+				break
+			}
+		}
+
+		rebased = append(rebased, flag)
+	}
+
+	return rebased
+}
+
+func rebasePath(directory, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(directory, path)
+}
@@ -0,0 +1,59 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/gabivlj/candice/pkg/logger"
+)
+
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGABRT: "SIGABRT",
+	syscall.SIGBUS:  "SIGBUS",
+	syscall.SIGFPE:  "SIGFPE",
+	syscall.SIGHUP:  "SIGHUP",
+	syscall.SIGILL:  "SIGILL",
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGKILL: "SIGKILL",
+	syscall.SIGPIPE: "SIGPIPE",
+	syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGSEGV: "SIGSEGV",
+	syscall.SIGTERM: "SIGTERM",
+	syscall.SIGTRAP: "SIGTRAP",
+}
+
+// exitStatus translates the error of waiting for the program into the exit code that candice
+// should exit with and, when the program didn't exit normally, a readable explanation.
+// Programs killed by a signal exit with 128 + signal number like shells do.
+func exitStatus(err error) (int, string) {
+	if err == nil {
+		return 0, ""
+	}
+
+	var exitError *exec.ExitError
+	if !errors.As(err, &exitError) {
+		return 1, err.Error()
+	}
+
+	if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		signal := status.Signal()
+		name, ok := signalNames[signal]
+		if !ok {
+			name = fmt.Sprintf("signal %d", int(signal))
+		}
+
+		return 128 + int(signal), fmt.Sprintf("program terminated by %s (%s)", name, signal.String())
+	}
+
+	return exitError.ExitCode(), ""
+}
+
+// exit writes the diagnostics that haven't been written yet and exits with code,
+// os.Exit doesn't run the deferred calls that would write them
+func exit(code int) {
+	logger.Flush()
+	os.Exit(code)
+}
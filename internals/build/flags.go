@@ -3,26 +3,100 @@ package build
 import (
 	"errors"
 	"os"
+	"strings"
 )
 
 type Flags struct {
 	Path    string
 	Mode    string
 	Release bool
+	NoCache bool
+	Watch   bool
+
+	// Format of the output of tree mode, text by default
+	Format string
+
+	// DiagnosticsFormat is how errors and warnings are written: text, json or sarif
+	DiagnosticsFormat string
+
+	// Symbols defined with -D NAME=value, they override the defines of candice.json
+	Defines map[string]string
+
+	// Arguments that are passed to the program on run mode, they come after '--'
+	ProgramArguments []string
 }
 
 func retrieveFlags() (Flags, error) {
-	flagsToReturn := Flags{}
+	flagsToReturn := Flags{Defines: map[string]string{}}
 	flags := os.Args
 	if len(flags) < 3 {
 		return flagsToReturn, errors.New("not enough arguments")
 	}
 	mode := flags[1]
 	path := flags[2]
-	for _, fl := range flags[2:] {
+	for i := 2; i < len(flags); i++ {
+		fl := flags[i]
+		if fl == "--" {
+			flagsToReturn.ProgramArguments = flags[i+1:]
+			break
+		}
+
+		if strings.HasPrefix(fl, "-D") {
+			define := strings.TrimPrefix(fl, "-D")
+			// -D NAME=value and -DNAME=value are the same
+			if define == "" {
+				if i+1 >= len(flags) {
+					return flagsToReturn, errors.New("expected NAME=value after -D")
+				}
+
+				i++
+				define = flags[i]
+			}
+
+			name, value, _ := strings.Cut(define, "=")
+			flagsToReturn.Defines[name] = value
+		}
+
 		if fl == "--release" {
 			flagsToReturn.Release = true
 		}
+
+		if fl == "--no-cache" {
+			flagsToReturn.NoCache = true
+		}
+
+		if fl == "--watch" {
+			flagsToReturn.Watch = true
+		}
+
+		if fl == "--format" || strings.HasPrefix(fl, "--format=") {
+			// --format json and --format=json are the same
+			format := strings.TrimPrefix(fl, "--format=")
+			if fl == "--format" {
+				if i+1 >= len(flags) {
+					return flagsToReturn, errors.New("expected text, json or dot after --format")
+				}
+
+				i++
+				format = flags[i]
+			}
+
+			flagsToReturn.Format = format
+		}
+
+		if fl == "--diagnostics-format" || strings.HasPrefix(fl, "--diagnostics-format=") {
+			format := strings.TrimPrefix(fl, "--diagnostics-format=")
+			if fl == "--diagnostics-format" {
+				if i+1 >= len(flags) {
+					return flagsToReturn, errors.New("expected text, json or sarif after --diagnostics-format")
+				}
+
+				i++
+				format = flags[i]
+			}
+
+			flagsToReturn.DiagnosticsFormat = format
+		}
 	}
 
 	flagsToReturn.Mode = mode
@@ -0,0 +1,134 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/gabivlj/candice/std"
+)
+
+// StdEnvironmentVariable overrides where the standard library is located
+const StdEnvironmentVariable = "CANDICE_STD"
+
+// locateStd returns the directory of the standard library, it looks for it on CANDICE_STD and
+// next to the candice executable. If it's not there, the copy of the standard library that is
+// embedded in the executable is extracted into the user cache directory.
+// It returns an empty string if it can't be found.
+func locateStd() string {
+	if directory := os.Getenv(StdEnvironmentVariable); directory != "" {
+		return directory
+	}
+
+	if executable, err := os.Executable(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+			executable = resolved
+		}
+
+		executableDirectory := filepath.Dir(executable)
+		for _, candidate := range []string{
+			filepath.Join(executableDirectory, "std"),
+			filepath.Join(executableDirectory, "..", "std"),
+			filepath.Join(executableDirectory, "..", "lib", "candice", "std"),
+		} {
+			if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+				return candidate
+			}
+		}
+	}
+
+	directory, err := extractStd()
+	if err != nil {
+		return ""
+	}
+
+	return directory
+}
+
+// extractStd writes the embedded standard library into the user cache directory. The directory
+// depends on the contents of the standard library, so different versions of candice don't
+// overwrite each other and an existing copy is never modified.
+func extractStd() (string, error) {
+	cacheDirectory, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	err = fs.WalkDir(std.Files, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		content, err := std.Files.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		hash.Write([]byte(filePath))
+		hash.Write([]byte{0})
+		hash.Write(content)
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	directory := filepath.Join(cacheDirectory, "candice", "std-"+Version+"-"+hex.EncodeToString(hash.Sum(nil))[:12])
+	if info, err := os.Stat(directory); err == nil && info.IsDir() {
+		return directory, nil
+	}
+
+	// Extract into a temporary directory first so an interrupted extraction
+	// doesn't leave an incomplete standard library behind.
+	temporaryDirectory, err := os.MkdirTemp(filepath.Dir(directory), "std-")
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(directory), 0o755); err != nil {
+			return "", err
+		}
+
+		temporaryDirectory, err = os.MkdirTemp(filepath.Dir(directory), "std-")
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	err = fs.WalkDir(std.Files, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		destination := filepath.Join(temporaryDirectory, filepath.FromSlash(filePath))
+		if entry.IsDir() {
+			return os.MkdirAll(destination, 0o755)
+		}
+
+		content, err := std.Files.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(destination, content, 0o644)
+	})
+
+	if err != nil {
+		os.RemoveAll(temporaryDirectory)
+		return "", err
+	}
+
+	if err := os.Rename(temporaryDirectory, directory); err != nil {
+		os.RemoveAll(temporaryDirectory)
+		// Another candice process might have extracted it at the same time
+		if info, statErr := os.Stat(directory); statErr == nil && info.IsDir() {
+			return directory, nil
+		}
+
+		return "", err
+	}
+
+	return directory, nil
+}
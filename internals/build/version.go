@@ -0,0 +1,5 @@
+package build
+
+// Version of the compiler, it's part of the key of every cached build artifact
+// so bumping it invalidates the build cache.
+const Version = "0.1.0"
@@ -0,0 +1,119 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gabivlj/candice/pkg/logger"
+)
+
+// pollInterval is how often watch mode checks the project files for changes
+const pollInterval = 300 * time.Millisecond
+
+// stopTimeout is how long the program has to exit after SIGTERM before it's killed
+const stopTimeout = 3 * time.Second
+
+// watch builds the project (and runs it on run mode) every time a file of the project changes.
+// The files that are watched are candice.json, the entrypoint and every file that it imports.
+func watch(flags Flags) {
+	for {
+		current := time.Now()
+		config, files, ok := buildProject(flags)
+		logger.Flush()
+		snapshot := takeSnapshot(files)
+
+		var program *exec.Cmd
+		exited := make(chan error, 1)
+		if ok && flags.Mode == "run" {
+			program, ok = startProgram(config, flags.ProgramArguments)
+			if ok {
+				go func(cmd *exec.Cmd) {
+					exited <- cmd.Wait()
+				}(program)
+			} else {
+				program = nil
+			}
+		} else if ok {
+			passedTime := float64(time.Now().UnixMilli() - current.UnixMilli())
+			logger.Success("BUILD SUCCESSFUL. (" + strconv.FormatFloat(passedTime/1000, 'f', 3, 64) + "s)")
+		}
+
+		logger.WarningNoTag(fmt.Sprintf("Watching %d files for changes...", len(snapshot)))
+		changed := waitForChanges(snapshot, exited, func(err error) {
+			program = nil
+			code, description := exitStatus(err)
+			if description != "" {
+				logger.Error("Running", description)
+			} else {
+				logger.WarningNoTag(fmt.Sprintf("Program finished with exit code %d", code))
+			}
+		})
+
+		if program != nil {
+			stopProgram(program, exited)
+		}
+
+		logger.Warning("Detected changes on " + changed + ", rebuilding...")
+	}
+}
+
+// stopProgram asks the program to exit with SIGTERM so it can clean up, and kills it
+// if it's still running after stopTimeout
+func stopProgram(program *exec.Cmd, exited <-chan error) {
+	if err := program.Process.Signal(syscall.SIGTERM); err != nil {
+		_ = program.Process.Kill()
+		<-exited
+		return
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(stopTimeout):
+		logger.Warning("Program didn't exit after SIGTERM, killing it")
+		_ = program.Process.Kill()
+		<-exited
+	}
+}
+
+// takeSnapshot stores the modification time of each file, files that don't exist
+// have the zero time so we know when they are created.
+func takeSnapshot(files []string) map[string]time.Time {
+	snapshot := map[string]time.Time{}
+	for _, file := range files {
+		snapshot[file] = modificationTime(file)
+	}
+
+	return snapshot
+}
+
+func modificationTime(file string) time.Time {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
+
+// waitForChanges blocks until one of the files of the snapshot changes and returns its path,
+// onExit is called if the running program exits meanwhile.
+func waitForChanges(snapshot map[string]time.Time, exited <-chan error, onExit func(error)) string {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			onExit(err)
+		case <-ticker.C:
+			for file, modTime := range snapshot {
+				if !modificationTime(file).Equal(modTime) {
+					return file
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package build
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/gabivlj/candice/pkg/a"
+)
+
+func TestWatch_StopProgram(t *testing.T) {
+	// the program cleans up and exits on its own when it receives SIGTERM
+	program := exec.Command("sh", "-c", `trap "exit 3" TERM; while true; do sleep 0.01; done`)
+	a.AssertErr(program.Start())
+	exited := make(chan error, 1)
+	go func() {
+		exited <- program.Wait()
+	}()
+
+	// give the shell time to set up the trap
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	stopProgram(program, exited)
+	a.Assert(time.Since(start) < stopTimeout, "the program should exit before being killed")
+	a.Assert(program.ProcessState.ExitCode() == 3, program.ProcessState)
+}
@@ -57,6 +57,10 @@ type Compiler struct {
 	modules                             map[string]*Compiler
 	compiledModules                     map[string]*Compiler
 
+	// owners keeps track of the module compiler that defined each function, so
+	// the IR can be split into a unit per module
+	owners map[*ir.Func]*Compiler
+
 	eventHandler func(Event)
 }
 
@@ -66,6 +70,7 @@ func New(context *semantic.Semantic, parent ...*Compiler) *Compiler {
 	var globalBuiltinDefinitions map[string]value.Value
 	var globalVariables map[string]*Value
 	var compiledModules map[string]*Compiler
+	var owners map[*ir.Func]*Compiler
 
 	if len(parent) > 0 {
 		// we need previous module to add llvm IR here.
@@ -83,12 +88,14 @@ func New(context *semantic.Semantic, parent ...*Compiler) *Compiler {
 		// let's remember those!
 		compiledModules = parent[0].compiledModules
 
+		owners = parent[0].owners
 	} else {
 		m = ir.NewModule()
 		globalVariables = map[string]*Value{}
 		builtins = map[string]func(*Compiler, *ast.BuiltinCall) value.Value{}
 		globalBuiltinDefinitions = map[string]value.Value{}
 		compiledModules = map[string]*Compiler{}
+		owners = map[*ir.Func]*Compiler{}
 	}
 
 	c := &Compiler{
@@ -103,6 +110,7 @@ func New(context *semantic.Semantic, parent ...*Compiler) *Compiler {
 		context:                  context,
 		modules:                  map[string]*Compiler{},
 		compiledModules:          compiledModules,
+		owners:                   owners,
 		eventHandler:             func(e Event) {},
 	}
 
@@ -187,7 +195,8 @@ func (c *Compiler) initializeBuiltinLib() {
 		ptr := c.loadIfPointer(c.compileExpression(call.Parameters[0]))
 		length := c.loadIfPointer(c.compileExpression(call.Parameters[1]))
 		length = c.handleIntegerCast(types.I64, length)
-		totalSize := c.block().NewMul(length, constant.NewInt(types.I64, typeParameter.SizeOf()))
+		elementSize := typeParameter.(*ctypes.Pointer).Inner.SizeOf()
+		totalSize := c.block().NewMul(length, constant.NewInt(types.I64, elementSize))
 		returnedValue := c.block().NewCall(c.realloc(), c.block().NewBitCast(ptr, types.I8Ptr), totalSize)
 		castedValue := c.block().NewBitCast(returnedValue, toReturnType)
 		alloca := c.block().NewAlloca(castedValue.Type())
@@ -243,6 +252,50 @@ func (c *Compiler) initializeBuiltinLib() {
 		return c.handleCast(call)
 	}
 
+	c.initializeVectorBuiltins()
+
+	c.builtins["atomic_load"] = func(c *Compiler, call *ast.BuiltinCall) value.Value {
+		ptr := c.loadIfPointer(c.compileExpression(call.Parameters[0]))
+		load := c.block().NewLoad(c.ToLLVMType(call.Type), ptr)
+		load.Atomic = true
+		load.Ordering = atomicOrdering(call.Parameters[1])
+		load.Align = ir.Align(call.Type.SizeOf())
+		return load
+	}
+
+	c.builtins["atomic_store"] = func(c *Compiler, call *ast.BuiltinCall) value.Value {
+		ptr := c.loadIfPointer(c.compileExpression(call.Parameters[0]))
+		val := c.loadIfPointer(c.compileExpression(call.Parameters[1]))
+		store := c.block().NewStore(val, ptr)
+		store.Atomic = true
+		store.Ordering = atomicOrdering(call.Parameters[2])
+		store.Align = ir.Align(call.Parameters[1].GetType().SizeOf())
+		return constant.NewUndef(types.Void)
+	}
+
+	// atomic_add returns the value before adding
+	c.builtins["atomic_add"] = func(c *Compiler, call *ast.BuiltinCall) value.Value {
+		ptr := c.loadIfPointer(c.compileExpression(call.Parameters[0]))
+		val := c.loadIfPointer(c.compileExpression(call.Parameters[1]))
+		return c.block().NewAtomicRMW(enum.AtomicOpAdd, ptr, val, atomicOrdering(call.Parameters[2]))
+	}
+
+	// atomic_cas stores the new value if the current one is equal to the expected one,
+	// it returns whether it stored it
+	c.builtins["atomic_cas"] = func(c *Compiler, call *ast.BuiltinCall) value.Value {
+		ptr := c.loadIfPointer(c.compileExpression(call.Parameters[0]))
+		expected := c.loadIfPointer(c.compileExpression(call.Parameters[1]))
+		newValue := c.loadIfPointer(c.compileExpression(call.Parameters[2]))
+		ordering := atomicOrdering(call.Parameters[3])
+		cmpXchg := c.block().NewCmpXchg(ptr, expected, newValue, ordering, atomicFailureOrdering(ordering))
+		return c.block().NewExtractValue(cmpXchg, 1)
+	}
+
+	c.builtins["fence"] = func(c *Compiler, call *ast.BuiltinCall) value.Value {
+		c.block().NewFence(atomicOrdering(call.Parameters[0]))
+		return constant.NewUndef(types.Void)
+	}
+
 }
 
 /// Public methods for the compiler
@@ -562,10 +615,12 @@ func (c *Compiler) compileFunctionType(name string, funk ast.Function) *ir.Func
 	toReturnType := c.ToLLVMType(functionType.Return)
 	// Declare llvmFunction
 	llvmFunction := c.m.NewFunc(functionType.Name, toReturnType, params...)
+	c.owners[llvmFunction] = c
 
 	if functionType.RedefineWithOriginalName {
 		llvmFunctionExtern := c.m.NewFunc(functionType.ExternalName, c.ToLLVMType(functionType.Return), params...)
 		llvmFunctionExtern.CallingConv = enum.CallingConvC
+		c.owners[llvmFunctionExtern] = c
 		c.globalVariables[functionType.ExternalName] = &Value{
 			Value: llvmFunctionExtern,
 			Type:  functionType,
@@ -1001,16 +1056,19 @@ func (c *Compiler) compilePrefixExpression(prefix *ast.PrefixOperation) value.Va
 
 func (c *Compiler) exitInternalError(message string) {
 	logger.Error("Internal error", "Unknown internal error has happened, check below for more details", "\n"+message)
+	logger.Flush()
 	os.Exit(1)
 }
 
 func (c *Compiler) exit(message string) {
 	logger.Error("Compiler error", "Unknown internal error has happened, check below for more details", "\n"+message)
+	logger.Flush()
 	os.Exit(1)
 }
 
 func (c *Compiler) exitErrorExpression(message string, node ast.Expression) {
 	logger.Error("Compiler", message, fmt.Sprintf("\non [%d:%d]: ", node.GetToken().Line, node.GetToken().Position)+node.String())
+	logger.Flush()
 	os.Exit(1)
 }
 
@@ -1051,11 +1109,11 @@ func (c *Compiler) retrieveVariable(name string) value.Value {
 			// If it is an array we must allocate it as a global and then cast it as a pointer
 			// and allocate it again so we can use it normally, we cache that result into globalVariables + .global
 			if types.IsArray(global.Type()) {
+				// the pointer is a constant so every function sees it, even if it runs before the first one that uses it
 				array := c.m.NewGlobalDef(name+".global_alloca", global)
-				pointer := c.block().NewBitCast(array, types.NewPointer(global.Type().(*types.ArrayType).ElemType))
-				globalDefinition := c.m.NewGlobalDef(name+".global", constant.NewNull(pointer.Type().(*types.PointerType)))
+				pointer := constant.NewBitCast(array, types.NewPointer(global.Type().(*types.ArrayType).ElemType))
+				globalDefinition := c.m.NewGlobalDef(name+".global", pointer)
 				c.globalVariables[name+".global"] = &Value{Value: globalDefinition, Type: fn.Type}
-				c.block().NewStore(pointer, globalDefinition)
 				return globalDefinition
 			}
 
@@ -1502,7 +1560,7 @@ func (c *Compiler) compileAdd(expr *ast.BinaryOperation) value.Value {
 	}
 
 	// numeric operations
-	if types.IsFloat(leftValue.Type()) {
+	if isFloat(leftValue.Type()) {
 		return c.block().NewFAdd(leftValue, rightValue)
 	}
 
@@ -1527,7 +1585,7 @@ func (c *Compiler) subtractOne(v value.Value) value.Value {
 func (c *Compiler) compileMultiply(expr *ast.BinaryOperation) value.Value {
 	leftValue := c.loadIfPointer(c.compileExpression(expr.Left))
 	rightValue := c.loadIfPointer(c.compileExpression(expr.Right))
-	if types.IsFloat(leftValue.Type()) {
+	if isFloat(leftValue.Type()) {
 		return c.block().NewFMul(leftValue, rightValue)
 	}
 	return c.block().NewMul(leftValue, rightValue)
@@ -1545,7 +1603,7 @@ func (c *Compiler) compileSubtract(expr *ast.BinaryOperation) value.Value {
 		return toReturnPointer
 	}
 
-	if types.IsFloat(leftValue.Type()) {
+	if isFloat(leftValue.Type()) {
 		return c.block().NewFSub(leftValue, rightValue)
 	}
 	return c.block().NewSub(leftValue, rightValue)
@@ -1554,13 +1612,13 @@ func (c *Compiler) compileSubtract(expr *ast.BinaryOperation) value.Value {
 func (c *Compiler) compileDivide(expr *ast.BinaryOperation) value.Value {
 	leftValue := c.loadIfPointer(c.compileExpression(expr.Left))
 	rightValue := c.loadIfPointer(c.compileExpression(expr.Right))
-	if types.IsInt(leftValue.Type()) {
-		if _, isUnsigned := expr.Type.(*ctypes.UInteger); isUnsigned {
+	if isInt(leftValue.Type()) {
+		if _, isUnsigned := elementType(expr.Type).(*ctypes.UInteger); isUnsigned {
 			return c.block().NewUDiv(leftValue, rightValue)
 		}
 		return c.block().NewSDiv(leftValue, rightValue)
 	}
-	if types.IsFloat(leftValue.Type()) {
+	if isFloat(leftValue.Type()) {
 		return c.block().NewFDiv(leftValue, rightValue)
 	}
 	c.exitErrorExpression("can't divide these types", expr)
@@ -1570,13 +1628,13 @@ func (c *Compiler) compileDivide(expr *ast.BinaryOperation) value.Value {
 func (c *Compiler) compileModulo(expr *ast.BinaryOperation) value.Value {
 	leftValue := c.loadIfPointer(c.compileExpression(expr.Left))
 	rightValue := c.loadIfPointer(c.compileExpression(expr.Right))
-	if types.IsInt(leftValue.Type()) {
-		if _, isUnsigned := expr.Type.(*ctypes.UInteger); isUnsigned {
+	if isInt(leftValue.Type()) {
+		if _, isUnsigned := elementType(expr.Type).(*ctypes.UInteger); isUnsigned {
 			return c.block().NewURem(leftValue, rightValue)
 		}
 		return c.block().NewSRem(leftValue, rightValue)
 	}
-	if types.IsFloat(leftValue.Type()) {
+	if isFloat(leftValue.Type()) {
 		return c.block().NewFRem(leftValue, rightValue)
 	}
 	c.exitErrorExpression("can't divide these types", expr)
@@ -1615,6 +1673,10 @@ func (c *Compiler) compileShiftLeftBinary(expr *ast.BinaryOperation) value.Value
 
 func (c *Compiler) handleCast(call *ast.BuiltinCall) value.Value {
 	typeParameter := call.TypeParameters[0]
+	if ctypes.IsVector(typeParameter) || ctypes.IsVector(call.Parameters[0].GetType()) {
+		return c.compileVectorCast(call)
+	}
+
 	toReturnType := c.ToLLVMType(typeParameter)
 	variable := c.compileExpression(call.Parameters[0])
 
@@ -1635,7 +1697,7 @@ func (c *Compiler) handleCast(call *ast.BuiltinCall) value.Value {
 		if ctypes.IsFloat(call.TypeParameters[0]) != ctypes.IsFloat(call.Parameters[0].GetType()) {
 			return c.handleFloatIntCast(call.TypeParameters[0], call.Parameters[0].GetType(), variable, toReturnType)
 		}
-		return c.handleNumericBitCast(toReturnType, variable)
+		return c.handleNumericBitCast(toReturnType, variable, ctypes.IsUnsignedInteger(call.Parameters[0].GetType()))
 	}
 
 	if ctypes.IsNumeric(call.TypeParameters[0]) && ctypes.IsPointer(call.Parameters[0].GetType()) {
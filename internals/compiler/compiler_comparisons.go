@@ -20,15 +20,17 @@ func (c *Compiler) handleComparisonOperations(expr *ast.BinaryOperation) value.V
 		}
 	}
 
-	if _, isFloat := expr.Left.GetType().(*ctypes.Float); isFloat {
+	// vectors are compared element by element
+	leftType := elementType(expr.Left.GetType())
+	if _, isFloat := leftType.(*ctypes.Float); isFloat {
 		return c.block().NewFCmp(
-			c.getFPredComparison(expr.Operation, expr.Left.GetType()),
+			c.getFPredComparison(expr.Operation, leftType),
 			c.loadIfPointer(c.compileExpression(expr.Left)),
 			c.loadIfPointer(c.compileExpression(expr.Right)),
 		)
 	}
 
-	return c.block().NewICmp(c.getIPredComparison(expr.Operation, expr.Left.GetType()),
+	return c.block().NewICmp(c.getIPredComparison(expr.Operation, leftType),
 		c.loadIfPointer(c.compileExpression(expr.Left)),
 		c.loadIfPointer(c.compileExpression(expr.Right)),
 	)
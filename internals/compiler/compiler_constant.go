@@ -3,163 +3,142 @@ package compiler
 import (
 	"github.com/gabivlj/candice/internals/ast"
 	"github.com/gabivlj/candice/internals/ctypes"
-
-	"github.com/gabivlj/candice/internals/ops"
+	"github.com/gabivlj/candice/internals/eval"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
 )
 
 func (c *Compiler) something() {
 	// constant.
 }
 
-func (c *Compiler) handleConstantCast(val constant.Constant, originalType ctypes.Type, to types.Type, expr ast.Expression) constant.Constant {
-	if types.IsInt(to) {
-		to := to.(*types.IntType)
-		if integerType, ok := val.Type().(*types.IntType); ok {
-			if integerType.BitSize > to.BitSize {
-				return constant.NewTrunc(val, to)
-			}
-
-			if integerType.BitSize < to.BitSize {
-				if val.Type() == types.I1 {
-					// SExt extends the bit sign of the val, i1 can be considered as a sign, so
-					// it will be extended accross all the bits, for example if you cast
-					// i1 '1' to i8, you will get 111...1110, which is -1.
-					return constant.NewZExt(val, to)
-				}
-
-				return constant.NewSExt(val, to)
-			}
+// constantEnvironment lets the evaluator use the constants that are already compiled
+type constantEnvironment struct {
+	c *Compiler
+}
 
-			return val
+func (e constantEnvironment) Constant(identifier *ast.Identifier) (eval.Value, bool) {
+	var compiled value.Value = e.c.retrieveLocalVariable(identifier.Name)
+	if compiled == nil {
+		global, ok := e.c.globalVariables[identifier.Name]
+		if !ok || !global.Constant {
+			return nil, false
 		}
 
-		if _, ok := val.Type().(*types.FloatType); ok {
-			if ctypes.IsUnsignedInteger(originalType) {
-				return constant.NewFPToUI(val, to)
-			}
-
-			return constant.NewFPToSI(val, to)
-		}
+		compiled = global.Value
+	}
 
-		c.exitErrorExpression("can't handle this cast", expr)
+	constantValue, isConstant := compiled.(constant.Constant)
+	if !isConstant {
+		return nil, false
 	}
 
-	if types.IsFloat(to) {
-		to := to.(*types.FloatType)
-		if _, ok := val.Type().(*types.IntType); ok {
-			if !ctypes.IsUnsignedInteger(originalType) {
-				return constant.NewSIToFP(val, to)
-			}
+	return e.c.constantToValue(constantValue, e.c.context.UnwrapAnonymous(identifier.Type))
+}
 
-			return constant.NewUIToFP(val, to)
+// constantToValue converts a compiled constant back to a value for the evaluator
+func (c *Compiler) constantToValue(compiled constant.Constant, t ctypes.Type) (eval.Value, bool) {
+	switch compiled := compiled.(type) {
+	case *constant.Int:
+		if compiled.X.IsInt64() {
+			return &eval.Integer{Value: compiled.X.Int64(), Type: t}, true
 		}
 
-		if valFloat, ok := val.Type().(*types.FloatType); ok {
-			if valFloat.Kind == types.FloatKindDouble && to.Kind == types.FloatKindFloat {
-				return constant.NewFPTrunc(val, to)
-			}
+		return &eval.Integer{Value: int64(compiled.X.Uint64()), Type: t}, true
+	case *constant.Float:
+		float, _ := compiled.X.Float64()
+		return &eval.Float{Value: float, Type: t}, true
+	case *constant.CharArray:
+		return &eval.String{Value: string(compiled.X[:len(compiled.X)-1])}, true
+	case *constant.Array:
+		array, isArray := c.context.UnwrapAnonymous(t).(*ctypes.Array)
+		if !isArray {
+			return nil, false
+		}
 
-			if valFloat.Kind == to.Kind {
-				return val
+		elements := make([]eval.Value, len(compiled.Elems))
+		for i, element := range compiled.Elems {
+			value, ok := c.constantToValue(element, c.context.UnwrapAnonymous(array.Inner))
+			if !ok {
+				return nil, false
 			}
 
-			return constant.NewFPExt(val, to)
+			elements[i] = value
 		}
+
+		return &eval.Array{Elements: elements, Type: array}, true
 	}
 
-	c.exitErrorExpression("can't handle this cast", expr)
-	return nil
+	return nil, false
 }
 
-func (c *Compiler) compileConstantExpression(expr ast.Expression) constant.Constant {
-	switch expr := expr.(type) {
-	case *ast.Identifier:
-		return c.compileIdentifier(expr).(constant.Constant)
-	case *ast.BinaryOperation:
-		left := c.compileConstantExpression(expr.Left)
-		right := c.compileConstantExpression(expr.Left)
-		return c.compileOperation(expr.Operation, expr, left, right)
-	case *ast.Integer:
-		return c.compileExpression(expr).(constant.Constant)
-	case *ast.Float:
-		return c.compileExpression(expr).(constant.Constant)
-	case *ast.StringLiteral:
-		return constant.NewCharArrayFromString(expr.Value + string(byte(0)))
-	case *ast.BuiltinCall:
-		if expr.Name == "cast" {
-			return c.handleConstantCast(
-				c.compileConstantExpression(expr.Parameters[0]),
-				expr.Parameters[0].GetType(),
-				c.ToLLVMType(expr.GetType()),
-				expr,
-			)
-		}
-		c.exitErrorExpression("can't handle this constant builtin call", expr)
-	default:
-		c.exitErrorExpression("unexpected non constant operation", expr)
-		panic(nil)
-	}
-	c.exitErrorExpression("can't handle this constant expression", expr)
-	panic(nil)
+func (e constantEnvironment) Type(t ctypes.Type) (ctypes.Type, bool) {
+	t = e.c.context.UnwrapAnonymous(t)
+	_, isAnonymous := t.(*ctypes.Anonymous)
+	return t, !isAnonymous
+}
+
+func (e constantEnvironment) Function(callee ast.Expression) (*ast.FunctionDeclarationStatement, eval.Environment, error) {
+	return e.c.context.ComptimeFunction(callee)
 }
 
-func (c *Compiler) compileOperation(op ops.Operation, expr *ast.BinaryOperation, first, second constant.Constant) constant.Constant {
-	switch op {
-	case ops.Add:
-		if types.IsFloat(first.Type()) {
-			return constant.NewFAdd(first, second)
+// compileConstantExpression evaluates the expression at compile time and returns its value
+func (c *Compiler) compileConstantExpression(expr ast.Expression) constant.Constant {
+	evaluated := eval.Evaluate(expr, constantEnvironment{c})
+	err, isError := evaluated.(*eval.Error)
+	if isError {
+		// functions are constants as well, but they aren't values that can be evaluated
+		if identifier, isIdentifier := expr.(*ast.Identifier); isIdentifier && ctypes.IsFunction(identifier.Type) {
+			return c.compileIdentifier(identifier).(constant.Constant)
 		}
 
-		return constant.NewAdd(first, second)
+		c.exitErrorExpression(err.Message, expr)
+		return nil
+	}
 
-	case ops.Subtract:
-		if types.IsFloat(first.Type()) {
-			return constant.NewFSub(first, second)
-		}
+	compiled := c.valueToConstant(evaluated, c.ToLLVMType(expr.GetType()))
+	if compiled == nil {
+		c.exitErrorExpression("can't handle this constant expression", expr)
+	}
 
-		return constant.NewSub(first, second)
+	return compiled
+}
 
-	case ops.Multiply:
-		if types.IsFloat(first.Type()) {
-			return constant.NewFMul(first, second)
+// valueToConstant converts a value of the evaluator to a constant of type t, it returns nil
+// for values that can't be constants
+func (c *Compiler) valueToConstant(evaluated eval.Value, t types.Type) constant.Constant {
+	switch evaluated := evaluated.(type) {
+	case *eval.Integer:
+		if integerType, isInteger := t.(*types.IntType); isInteger {
+			return constant.NewInt(integerType, evaluated.Value)
 		}
-
-		return constant.NewMul(first, second)
-
-	case ops.BinaryAND:
-		return constant.NewAnd(first, second)
-	case ops.BinaryXOR:
-		return constant.NewXor(first, second)
-	case ops.BinaryOR:
-		return constant.NewOr(first, second)
-	case ops.LeftShift:
-		return constant.NewShl(first, second)
-	case ops.RightShift:
-		return constant.NewLShr(first, second)
-	case ops.Divide:
-		if types.IsInt(first.Type()) {
-			if _, isUnsigned := expr.Type.(*ctypes.UInteger); isUnsigned {
-				return constant.NewUDiv(first, second)
-			}
-			return constant.NewSDiv(first, second)
+	case *eval.Float:
+		if floatType, isFloat := t.(*types.FloatType); isFloat {
+			return constant.NewFloat(floatType, evaluated.Value)
 		}
-		if types.IsFloat(first.Type()) {
-			return constant.NewFDiv(first, second)
+	case *eval.String:
+		return constant.NewCharArrayFromString(evaluated.Value + string(byte(0)))
+	case *eval.Array:
+		arrayType, isArray := t.(*types.ArrayType)
+		if !isArray {
+			return nil
 		}
-	case ops.Modulo:
-		if types.IsInt(first.Type()) {
-			if _, isUnsigned := expr.Type.(*ctypes.UInteger); isUnsigned {
-				return constant.NewURem(first, second)
+
+		elements := make([]constant.Constant, len(evaluated.Elements))
+		for i, element := range evaluated.Elements {
+			// strings are pointers to globals inside of arrays
+			if _, isString := element.(*eval.String); isString {
+				return nil
+			}
+
+			if elements[i] = c.valueToConstant(element, arrayType.ElemType); elements[i] == nil {
+				return nil
 			}
-			return constant.NewSRem(first, second)
-		}
-		if types.IsFloat(first.Type()) {
-			return constant.NewFRem(first, second)
 		}
+
+		return constant.NewArray(arrayType, elements...)
 	}
 
-	c.exitInternalError("can't handle operation " + op.String() + " on " + first.String() + " and " + second.String())
 	return nil
 }
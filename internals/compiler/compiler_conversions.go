@@ -45,9 +45,14 @@ func (c *Compiler) handleIntegerCast(toReturnType *types.IntType, variable value
 // performs a cast bitsize, if first parameter is a float the second param should be a float as well
 // if you call this function and toReturnType is not
 // a float or an integer the program will probably panic
-func (c *Compiler) handleNumericBitCast(toReturnType types.Type, variable value.Value) value.Value {
+func (c *Compiler) handleNumericBitCast(toReturnType types.Type, variable value.Value, isUnsigned bool) value.Value {
 	integerType, isInteger := toReturnType.(*types.IntType)
 	if isInteger {
+		// unsigned integers are extended with zeros, like i1
+		if isUnsigned && variable.Type().(*types.IntType).BitSize < integerType.BitSize {
+			return c.block().NewZExt(variable, integerType)
+		}
+
 		return c.handleIntegerCast(integerType, variable)
 	}
 	return c.handleFloatCast(toReturnType.(*types.FloatType), variable)
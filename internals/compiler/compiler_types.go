@@ -139,6 +139,10 @@ func (c *Compiler) ToLLVMType(t ctypes.Type) types.Type {
 		{
 			return types.NewArray(uint64(el.Length), c.ToLLVMType(el.Inner))
 		}
+	case *ctypes.Vector:
+		{
+			return types.NewVector(uint64(el.Length), c.ToLLVMType(el.Inner))
+		}
 	case *ctypes.Void:
 		{
 			return types.Void
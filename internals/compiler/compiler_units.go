@@ -0,0 +1,82 @@
+package compiler
+
+import (
+	"sort"
+
+	"github.com/gabivlj/candice/internals/semantic"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/enum"
+)
+
+// Unit is the LLVM IR that belongs to a single candice module.
+// Every unit can be compiled on its own into an object file, functions
+// of other modules are declared but not defined.
+type Unit struct {
+	Module *semantic.Semantic
+	IR     *ir.Module
+}
+
+// Units splits the compiled program into a unit per module. The root module
+// is always the first unit.
+//
+// Functions are only defined on the unit of the module that declares them.
+// Global definitions can be created lazily by whichever module accesses them first,
+// so every unit carries its own copy with linkonce_odr linkage and the linker keeps one of them.
+func (c *Compiler) Units() []Unit {
+	compilers := []*Compiler{c}
+	seen := map[*Compiler]struct{}{c: {}}
+	ids := make([]string, 0, len(c.compiledModules))
+	for id := range c.compiledModules {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	for _, id := range ids {
+		module := c.compiledModules[id]
+		if _, ok := seen[module]; ok {
+			continue
+		}
+
+		seen[module] = struct{}{}
+		compilers = append(compilers, module)
+	}
+
+	units := make([]Unit, 0, len(compilers))
+	for _, module := range compilers {
+		units = append(units, Unit{Module: module.context, IR: c.unitOf(module)})
+	}
+
+	return units
+}
+
+func (c *Compiler) unitOf(module *Compiler) *ir.Module {
+	unit := ir.NewModule()
+	unit.TypeDefs = c.m.TypeDefs
+	for _, global := range c.m.Globals {
+		globalCopy := *global
+		if globalCopy.Init != nil {
+			globalCopy.Linkage = enum.LinkageLinkOnceODR
+		}
+
+		unit.Globals = append(unit.Globals, &globalCopy)
+	}
+
+	for _, function := range c.m.Funcs {
+		if c.owners[function] == module && len(function.Blocks) > 0 {
+			unit.Funcs = append(unit.Funcs, function)
+			continue
+		}
+
+		params := make([]*ir.Param, 0, len(function.Params))
+		for _, param := range function.Params {
+			params = append(params, ir.NewParam("", param.Typ))
+		}
+
+		declaration := ir.NewFunc(function.Name(), function.Sig.RetType, params...)
+		declaration.Sig.Variadic = function.Sig.Variadic
+		declaration.CallingConv = function.CallingConv
+		unit.Funcs = append(unit.Funcs, declaration)
+	}
+
+	return unit
+}
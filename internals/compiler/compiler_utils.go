@@ -1,6 +1,7 @@
 package compiler
 
 import (
+	"github.com/gabivlj/candice/internals/ast"
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/enum"
@@ -170,3 +171,29 @@ func (c *Compiler) asm(outputType types.Type, inlineAsm string, params ...value.
 	inlineAsmCall := ir.NewInlineAsm(types.NewPointer(types.NewFunc(outputType, paramTypes...)), inlineAsm, constraintOutput+"~{dirflag},~{fpsr},~{flags}")
 	return c.block().NewCall(inlineAsmCall, params...)
 }
+
+var atomicOrderings = map[string]enum.AtomicOrdering{
+	"relaxed": enum.AtomicOrderingMonotonic,
+	"acquire": enum.AtomicOrderingAcquire,
+	"release": enum.AtomicOrderingRelease,
+	"acq_rel": enum.AtomicOrderingAcqRel,
+	"seq_cst": enum.AtomicOrderingSeqCst,
+}
+
+// atomicOrdering returns the ordering of an atomic builtin, semantic already checked that it's valid
+func atomicOrdering(ordering ast.Expression) enum.AtomicOrdering {
+	return atomicOrderings[ordering.(*ast.Identifier).Token.Literal]
+}
+
+// atomicFailureOrdering returns the ordering of a failed compare and swap, it can't release
+// because nothing is stored.
+func atomicFailureOrdering(ordering enum.AtomicOrdering) enum.AtomicOrdering {
+	switch ordering {
+	case enum.AtomicOrderingAcqRel:
+		return enum.AtomicOrderingAcquire
+	case enum.AtomicOrderingRelease:
+		return enum.AtomicOrderingMonotonic
+	}
+
+	return ordering
+}
@@ -0,0 +1,192 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/gabivlj/candice/internals/ast"
+	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// isFloat is like types.IsFloat but it also accepts vectors of floats
+func isFloat(t types.Type) bool {
+	if vector, isVector := t.(*types.VectorType); isVector {
+		return types.IsFloat(vector.ElemType)
+	}
+
+	return types.IsFloat(t)
+}
+
+// isInt is like types.IsInt but it also accepts vectors of integers
+func isInt(t types.Type) bool {
+	if vector, isVector := t.(*types.VectorType); isVector {
+		return types.IsInt(vector.ElemType)
+	}
+
+	return types.IsInt(t)
+}
+
+// elementType returns the type of the elements of a vector, or the type itself if it isn't a vector
+func elementType(t ctypes.Type) ctypes.Type {
+	if vector, isVector := t.(*ctypes.Vector); isVector {
+		return vector.Inner
+	}
+
+	return t
+}
+
+func (c *Compiler) initializeVectorBuiltins() {
+	c.builtins["splat"] = func(c *Compiler, call *ast.BuiltinCall) value.Value {
+		vectorType := c.ToLLVMType(call.Type).(*types.VectorType)
+		element := c.loadIfPointer(c.compileExpression(call.Parameters[0]))
+		vector := c.block().NewInsertElement(constant.NewUndef(vectorType), element, constant.NewInt(types.I32, 0))
+		mask := constant.NewZeroInitializer(types.NewVector(vectorType.Len, types.I32))
+		return c.block().NewShuffleVector(vector, constant.NewUndef(vectorType), mask)
+	}
+
+	c.builtins["extract"] = func(c *Compiler, call *ast.BuiltinCall) value.Value {
+		vector := c.loadIfPointer(c.compileExpression(call.Parameters[0]))
+		index := c.loadIfPointer(c.compileExpression(call.Parameters[1]))
+		return c.block().NewExtractElement(vector, index)
+	}
+
+	c.builtins["insert"] = func(c *Compiler, call *ast.BuiltinCall) value.Value {
+		vector := c.loadIfPointer(c.compileExpression(call.Parameters[0]))
+		index := c.loadIfPointer(c.compileExpression(call.Parameters[1]))
+		element := c.loadIfPointer(c.compileExpression(call.Parameters[2]))
+		return c.block().NewInsertElement(vector, element, index)
+	}
+
+	c.builtins["shuffle"] = func(c *Compiler, call *ast.BuiltinCall) value.Value {
+		first := c.loadIfPointer(c.compileExpression(call.Parameters[0]))
+		second := c.loadIfPointer(c.compileExpression(call.Parameters[1]))
+		indexes := make([]constant.Constant, 0, len(call.Parameters)-2)
+		for _, index := range call.Parameters[2:] {
+			indexes = append(indexes, constant.NewInt(types.I32, index.(*ast.Integer).Value))
+		}
+
+		mask := constant.NewVector(types.NewVector(uint64(len(indexes)), types.I32), indexes...)
+		return c.block().NewShuffleVector(first, second, mask)
+	}
+
+	c.builtins["reduce"] = func(c *Compiler, call *ast.BuiltinCall) value.Value {
+		vector := c.loadIfPointer(c.compileExpression(call.Parameters[0]))
+		operation := call.Parameters[1].(*ast.Identifier).Token.Literal
+		// the type of the call is the type of the elements
+		return c.reduceVector(vector, call.Type, operation)
+	}
+}
+
+// reduceVector combines every element of the vector with an llvm.vector.reduce intrinsic
+func (c *Compiler) reduceVector(vector value.Value, inner ctypes.Type, operation string) value.Value {
+	vectorType := vector.Type().(*types.VectorType)
+	_, isFloat := inner.(*ctypes.Float)
+	intrinsic := operation
+	switch {
+	case isFloat:
+		intrinsic = "f" + operation
+	case operation == "min" || operation == "max":
+		if ctypes.IsUnsignedInteger(inner) {
+			intrinsic = "u" + operation
+		} else {
+			intrinsic = "s" + operation
+		}
+	}
+
+	name := fmt.Sprintf("llvm.vector.reduce.%s.v%d%s", intrinsic, vectorType.Len, vectorType.ElemType.LLString())
+	parameters := []*ir.Param{ir.NewParam("", vectorType)}
+	arguments := []value.Value{vector}
+	// fadd and fmul are sequential and start from an initial value
+	if intrinsic == "fadd" || intrinsic == "fmul" {
+		start := -0.0
+		if intrinsic == "fmul" {
+			start = 1.0
+		}
+
+		elementType := vectorType.ElemType.(*types.FloatType)
+		parameters = append([]*ir.Param{ir.NewParam("", elementType)}, parameters...)
+		arguments = append([]value.Value{constant.NewFloat(elementType, start)}, arguments...)
+	}
+
+	fn, ok := c.globalBuiltinDefinitions[name]
+	if !ok {
+		fn = c.m.NewFunc(name, vectorType.ElemType, parameters...)
+		c.globalBuiltinDefinitions[name] = fn
+	}
+
+	return c.block().NewCall(fn, arguments...)
+}
+
+// compileVectorCast casts arrays into vectors and the other way around by reinterpreting their memory,
+// and converts vectors of numbers into vectors of other numbers.
+func (c *Compiler) compileVectorCast(call *ast.BuiltinCall) value.Value {
+	toType := call.TypeParameters[0]
+	toReturnType := c.ToLLVMType(toType)
+	variable := c.compileExpression(call.Parameters[0])
+	fromVector, isFromVector := call.Parameters[0].GetType().(*ctypes.Vector)
+	toVector, isToVector := toType.(*ctypes.Vector)
+	if !isFromVector {
+		// arrays are usually already on memory, so the vector is loaded from there
+		if !types.IsPointer(variable.Type()) {
+			alloca := c.block().NewAlloca(variable.Type())
+			c.block().NewStore(variable, alloca)
+			variable = alloca
+		}
+
+		load := c.block().NewLoad(toReturnType, c.block().NewBitCast(variable, types.NewPointer(toReturnType)))
+		load.Align = ir.Align(toVector.Inner.SizeOf())
+		return load
+	}
+
+	vector := c.loadIfPointer(variable)
+	if !isToVector {
+		alloca := c.block().NewAlloca(toReturnType)
+		store := c.block().NewStore(vector, c.block().NewBitCast(alloca, types.NewPointer(vector.Type())))
+		store.Align = ir.Align(fromVector.Inner.SizeOf())
+		return alloca
+	}
+
+	return c.convertVector(vector, fromVector.Inner, toVector.Inner, toReturnType)
+}
+
+func (c *Compiler) convertVector(vector value.Value, from, to ctypes.Type, toReturnType types.Type) value.Value {
+	fromFloat, isFromFloat := from.(*ctypes.Float)
+	toFloat, isToFloat := to.(*ctypes.Float)
+	switch {
+	case isFromFloat && isToFloat:
+		if fromFloat.BitSize > toFloat.BitSize {
+			return c.block().NewFPTrunc(vector, toReturnType)
+		} else if fromFloat.BitSize < toFloat.BitSize {
+			return c.block().NewFPExt(vector, toReturnType)
+		}
+
+		return vector
+	case isFromFloat:
+		if ctypes.IsUnsignedInteger(to) {
+			return c.block().NewFPToUI(vector, toReturnType)
+		}
+
+		return c.block().NewFPToSI(vector, toReturnType)
+	case isToFloat:
+		if ctypes.IsUnsignedInteger(from) {
+			return c.block().NewUIToFP(vector, toReturnType)
+		}
+
+		return c.block().NewSIToFP(vector, toReturnType)
+	}
+
+	fromSize, toSize := from.SizeOf(), to.SizeOf()
+	switch {
+	case fromSize > toSize:
+		return c.block().NewTrunc(vector, toReturnType)
+	case fromSize < toSize && ctypes.IsUnsignedInteger(from):
+		return c.block().NewZExt(vector, toReturnType)
+	case fromSize < toSize:
+		return c.block().NewSExt(vector, toReturnType)
+	}
+
+	return vector
+}
@@ -186,6 +186,47 @@ func (a *Array) Alignment() int64 {
 
 func (_ *Array) CandiceType() {}
 
+// Vector is a fixed number of integers or floats that are operated at the same time with SIMD instructions,
+// it's written as vec4 f32.
+type Vector struct {
+	Inner  Type
+	Length int64
+}
+
+func (v *Vector) String() string {
+	return fmt.Sprintf("vec%d %s", v.Length, v.Inner.String())
+}
+
+func (v *Vector) SizeOf() int64 {
+	if v.Inner == I1 {
+		return (v.Length + 7) / 8
+	}
+
+	return v.Inner.SizeOf() * v.Length
+}
+
+// Alignment of vectors is their size, like in LLVM
+func (v *Vector) Alignment() int64 {
+	return v.SizeOf()
+}
+
+func (_ *Vector) CandiceType() {}
+
+// VectorLength returns the length of the vector type literal (vec2, vec4... vec64)
+func VectorLength(literal string) (int64, bool) {
+	if !strings.HasPrefix(literal, "vec") {
+		return 0, false
+	}
+
+	for length := int64(2); length <= 64; length *= 2 {
+		if literal == fmt.Sprintf("vec%d", length) {
+			return length, true
+		}
+	}
+
+	return 0, false
+}
+
 type Function struct {
 	Name                     string
 	ExternalName             string
@@ -194,6 +235,9 @@ type Function struct {
 	Names                    []string
 	Parameters               []Type
 	Return                   Type
+
+	// Comptime functions can also be called at compile time, for example to initialize constants
+	Comptime bool
 }
 
 func (f *Function) IsMainFunction() bool {
@@ -212,6 +256,10 @@ func (f *Function) Alignment() int64 {
 
 func (f *Function) FullString() string {
 	builder := strings.Builder{}
+	if f.Comptime {
+		builder.WriteString("comptime ")
+	}
+
 	builder.WriteString("func ")
 	if f.Name != "" {
 		builder.WriteString(helper.RetrieveID(f.Name))
@@ -468,6 +516,11 @@ func IsUnsignedInteger(t Type) bool {
 	return ok
 }
 
+func IsVector(t Type) bool {
+	_, ok := t.(*Vector)
+	return ok
+}
+
 func IsFunction(t Type) bool {
 	_, ok := t.(*Function)
 	return ok
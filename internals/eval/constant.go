@@ -1,32 +1,513 @@
 package eval
 
 import (
+	"fmt"
+	"math"
+
 	"github.com/gabivlj/candice/internals/ast"
+	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/ops"
 )
 
-func EvaluateConstantExpression(expression ast.Expression) Value {
+// Environment resolves what the evaluator can't know by itself. The parser, the semantic analyzer
+// and the compiler have their own environment, but the rules to evaluate an expression are the same.
+type Environment interface {
+	// Constant returns the value of the constant that the identifier references
+	Constant(identifier *ast.Identifier) (Value, bool)
+
+	// Type resolves types that aren't builtin, like aliases or structs
+	Type(t ctypes.Type) (ctypes.Type, bool)
+
+	// Function returns the comptime function that the callee references and the environment
+	// of the module where it's declared, see ComptimeFunction.
+	Function(callee ast.Expression) (*ast.FunctionDeclarationStatement, Environment, error)
+}
+
+// evaluator evaluates expressions, while running comptime functions it also has their variables
+type evaluator struct {
+	environment Environment
+
+	// scopes are the scopes of the comptime function that is running, the innermost one is the last
+	scopes []map[string]*Value
+
+	// steps is shared by every function that runs because of the same expression
+	steps *int
+	depth int
+}
+
+// Evaluate evaluates a constant expression, it returns an *Error if the expression can't
+// be evaluated at compile time.
+func Evaluate(expression ast.Expression, environment Environment) Value {
+	e := &evaluator{environment: environment, steps: new(int)}
+	return e.evaluate(expression)
+}
+
+func (e *evaluator) evaluate(expression ast.Expression) Value {
+	if err := e.step(expression); err != nil {
+		return err
+	}
+
 	switch typedExpression := expression.(type) {
 	case *ast.Integer:
 		{
-			return &Integer{typedExpression.Value}
+			return wrap(typedExpression.Value, typedExpression.Type)
+		}
+
+	case *ast.Float:
+		{
+			return newFloat(typedExpression.Value, typedExpression.Type)
+		}
+
+	case *ast.StringLiteral:
+		{
+			return &String{typedExpression.Value}
 		}
 
 	case *ast.Identifier:
 		{
-			value, ok := constants[ast.RetrieveID(typedExpression.Name)]
+			if variable := e.variable(typedExpression.Name); variable != nil {
+				return *variable
+			}
+
+			value, ok := e.environment.Constant(typedExpression)
 			if !ok {
-				return &Error{
-					Message: "unknown identifier for constant expression" + expression.String(),
-					Token:   expression.GetToken(),
-				}
+				return newError("unknown constant "+typedExpression.Token.Literal, expression)
+			}
+
+			if err, isError := value.(*Error); isError {
+				return newError(typedExpression.Token.Literal+" can't be evaluated at compile time: "+err.Message, expression)
 			}
 
 			return value
 		}
+
+	case *ast.PrefixOperation:
+		{
+			return e.evaluatePrefixOperation(typedExpression)
+		}
+
+	case *ast.BinaryOperation:
+		{
+			return e.evaluateBinaryOperation(typedExpression)
+		}
+
+	case *ast.BuiltinCall:
+		{
+			return e.evaluateBuiltinCall(typedExpression)
+		}
+
+	case *ast.ArrayLiteral:
+		{
+			return e.evaluateArrayLiteral(typedExpression)
+		}
+
+	case *ast.IndexAccess:
+		{
+			return e.evaluateIndexAccess(typedExpression)
+		}
+
+	case *ast.Call:
+		{
+			value := e.call(typedExpression)
+			if value == nil {
+				return newError(typedExpression.Left.String()+" doesn't return a value", expression)
+			}
+
+			return value
+		}
+	}
+
+	return newError("can't evaluate expression "+describe(expression)+" at compile time", expression)
+}
+
+func newError(message string, expression ast.Expression) *Error {
+	return &Error{Message: message, Token: expression.GetToken()}
+}
+
+// describe returns the source of the node for error messages, the node might be incomplete
+// after a syntax error so when the source isn't known it falls back to its token
+func describe(node ast.Node) (description string) {
+	if source := node.Span().Source(); source != "" {
+		return source
+	}
+
+	defer func() {
+		if recover() != nil {
+			description = node.GetToken().Literal
+		}
+	}()
+
+	return node.String()
+}
+
+func newFloat(value float64, t ctypes.Type) *Float {
+	if float, isFloat := t.(*ctypes.Float); isFloat && float.BitSize == 32 {
+		value = float64(float32(value))
+	}
+
+	return &Float{Value: value, Type: t}
+}
+
+// wrap truncates the integer to the bit width of its type, signed integers keep their sign
+// and unsigned integers are zero extended, u64 keeps the bits of the value.
+func wrap(value int64, t ctypes.Type) *Integer {
+	switch t := t.(type) {
+	case *ctypes.Integer:
+		if t.BitSize == 1 {
+			return &Integer{Value: value & 1, Type: t}
+		}
+
+		shift := 64 - t.BitSize
+		return &Integer{Value: value << shift >> shift, Type: t}
+	case *ctypes.UInteger:
+		if t.BitSize < 64 {
+			value &= 1<<t.BitSize - 1
+		}
+	}
+
+	return &Integer{Value: value, Type: t}
+}
+
+func isUnsigned(t ctypes.Type) bool {
+	if integer, isInteger := t.(*ctypes.Integer); isInteger {
+		return integer.BitSize == 1
+	}
+
+	return ctypes.IsUnsignedInteger(t)
+}
+
+func bitSize(t ctypes.Type) uint {
+	switch t := t.(type) {
+	case *ctypes.Integer:
+		return t.BitSize
+	case *ctypes.UInteger:
+		return t.BitSize
+	}
+
+	return 64
+}
+
+func newBool(value bool) *Integer {
+	return &Integer{Value: boolToInteger(value), Type: ctypes.I1}
+}
+
+func (e *evaluator) evaluatePrefixOperation(expression *ast.PrefixOperation) Value {
+	if expression.Operation == ops.AddOne || expression.Operation == ops.SubtractOne {
+		return e.increment(expression)
+	}
+
+	value := e.evaluate(expression.Right)
+	if _, isError := value.(*Error); isError {
+		return value
+	}
+
+	switch expression.Operation {
+	case ops.Bang:
+		return newBool(!value.IsTruthy())
+	case ops.Add:
+		switch value.(type) {
+		case *Integer, *Float:
+			return value
+		}
+	case ops.Subtract:
+		switch value := value.(type) {
+		case *Integer:
+			return wrap(-value.Value, value.Type)
+		case *Float:
+			return newFloat(-value.Value, value.Type)
+		}
+	}
+
+	return newError("can't evaluate expression "+describe(expression)+" at compile time", expression)
+}
+
+func (e *evaluator) evaluateBinaryOperation(expression *ast.BinaryOperation) Value {
+	left := e.evaluate(expression.Left)
+	if _, isError := left.(*Error); isError {
+		return left
+	}
+
+	// && and || don't evaluate the right side if the left one decides the result,
+	// so #if WINDOWS && WINDOWS_ONLY_SYMBOL works on every platform
+	if expression.Operation == ops.AND && !left.IsTruthy() {
+		return newBool(false)
+	}
+
+	if expression.Operation == ops.OR && left.IsTruthy() {
+		return newBool(true)
 	}
 
-	return &Error{
-		Message: "can't evaluate expression " + expression.String(),
-		Token:   expression.GetToken(),
+	right := e.evaluate(expression.Right)
+	if _, isError := right.(*Error); isError {
+		return right
 	}
+
+	if expression.Operation == ops.AND || expression.Operation == ops.OR {
+		return newBool(right.IsTruthy())
+	}
+
+	switch left := left.(type) {
+	case *Integer:
+		if right, ok := right.(*Integer); ok {
+			return evaluateIntegerOperation(expression, left, right)
+		}
+
+	case *Float:
+		if right, ok := right.(*Float); ok {
+			return evaluateFloatOperation(expression, left, right)
+		}
+
+	case *String:
+		if right, ok := right.(*String); ok {
+			switch expression.Operation {
+			case ops.Equals:
+				return newBool(left.Value == right.Value)
+			case ops.NotEquals:
+				return newBool(left.Value != right.Value)
+			}
+		}
+	}
+
+	return newError("can't evaluate expression "+describe(expression)+
+		", integers and floats can be operated with each other and strings can only be compared with == and !=", expression)
+}
+
+func evaluateIntegerOperation(expression *ast.BinaryOperation, left, right *Integer) Value {
+	if expression.Operation == ops.LeftShift || expression.Operation == ops.RightShift {
+		return evaluateShift(expression, left, right)
+	}
+
+	// integers without type take the type of the other side
+	t := left.Type
+	if t == nil {
+		t = right.Type
+	} else if right.Type != nil && t.String() != right.Type.String() {
+		return newError(fmt.Sprintf("mismatched types %s and %s on %s", left.Type, right.Type, expression), expression)
+	}
+
+	l, r := left.Value, right.Value
+	if isUnsigned(t) {
+		if result, isComparison := compareUnsigned(expression.Operation, uint64(l), uint64(r)); isComparison {
+			return newBool(result)
+		}
+	} else if result, isComparison := compareIntegers(expression.Operation, l, r); isComparison {
+		return newBool(result)
+	}
+
+	switch expression.Operation {
+	case ops.Add:
+		return wrap(l+r, t)
+	case ops.Subtract:
+		return wrap(l-r, t)
+	case ops.Multiply:
+		return wrap(l*r, t)
+	case ops.BinaryAND:
+		return wrap(l&r, t)
+	case ops.BinaryOR:
+		return wrap(l|r, t)
+	case ops.BinaryXOR:
+		return wrap(l^r, t)
+	case ops.Divide, ops.Modulo:
+		if r == 0 {
+			return newError("division by zero on "+describe(expression), expression)
+		}
+
+		if isUnsigned(t) {
+			if expression.Operation == ops.Divide {
+				return wrap(int64(uint64(l)/uint64(r)), t)
+			}
+
+			return wrap(int64(uint64(l)%uint64(r)), t)
+		}
+
+		if expression.Operation == ops.Divide {
+			return wrap(l/r, t)
+		}
+
+		return wrap(l%r, t)
+	}
+
+	return newError("can't evaluate expression "+describe(expression)+" at compile time", expression)
+}
+
+// evaluateShift shifts the left integer, like at runtime the amount doesn't need to have its type
+func evaluateShift(expression *ast.BinaryOperation, left, right *Integer) Value {
+	t := left.Type
+	if t == nil {
+		t = right.Type
+	}
+
+	l, r := left.Value, right.Value
+	size := bitSize(t)
+	if r < 0 || uint64(r) >= uint64(size) {
+		return newError(fmt.Sprintf("can't shift a %d bit integer by %d on %s", size, r, expression), expression)
+	}
+
+	if expression.Operation == ops.LeftShift {
+		return wrap(l<<r, t)
+	}
+
+	// >> is a logical shift like at runtime, the bits outside of the type are cleared before shifting
+	bits := uint64(l)
+	if size < 64 {
+		bits &= 1<<size - 1
+	}
+
+	return wrap(int64(bits>>r), t)
+}
+
+func evaluateFloatOperation(expression *ast.BinaryOperation, left, right *Float) Value {
+	if left.Type.String() != right.Type.String() {
+		return newError(fmt.Sprintf("mismatched types %s and %s on %s", left.Type, right.Type, expression), expression)
+	}
+
+	l, r := left.Value, right.Value
+	switch expression.Operation {
+	case ops.Equals:
+		return newBool(l == r)
+	case ops.NotEquals:
+		return newBool(l != r)
+	case ops.GreaterThan:
+		return newBool(l > r)
+	case ops.GreaterThanEqual:
+		return newBool(l >= r)
+	case ops.LessThan:
+		return newBool(l < r)
+	case ops.LessThanEqual:
+		return newBool(l <= r)
+	case ops.Add:
+		return newFloat(l+r, left.Type)
+	case ops.Subtract:
+		return newFloat(l-r, left.Type)
+	case ops.Multiply:
+		return newFloat(l*r, left.Type)
+	case ops.Divide:
+		return newFloat(l/r, left.Type)
+	case ops.Modulo:
+		return newFloat(math.Mod(l, r), left.Type)
+	}
+
+	return newError("can't evaluate expression "+describe(expression)+" at compile time", expression)
+}
+
+func compareIntegers(operation ops.Operation, left, right int64) (bool, bool) {
+	switch operation {
+	case ops.Equals:
+		return left == right, true
+	case ops.NotEquals:
+		return left != right, true
+	case ops.GreaterThan:
+		return left > right, true
+	case ops.GreaterThanEqual:
+		return left >= right, true
+	case ops.LessThan:
+		return left < right, true
+	case ops.LessThanEqual:
+		return left <= right, true
+	}
+
+	return false, false
+}
+
+func compareUnsigned(operation ops.Operation, left, right uint64) (bool, bool) {
+	switch operation {
+	case ops.Equals:
+		return left == right, true
+	case ops.NotEquals:
+		return left != right, true
+	case ops.GreaterThan:
+		return left > right, true
+	case ops.GreaterThanEqual:
+		return left >= right, true
+	case ops.LessThan:
+		return left < right, true
+	case ops.LessThanEqual:
+		return left <= right, true
+	}
+
+	return false, false
+}
+
+func (e *evaluator) evaluateBuiltinCall(call *ast.BuiltinCall) Value {
+	switch call.Name {
+	case "sizeof":
+		t, ok := e.resolveType(call.TypeParameters[0])
+		if !ok {
+			return newError("can't know the size of "+call.TypeParameters[0].String()+" at compile time", call)
+		}
+
+		return &Integer{Value: t.SizeOf(), Type: ctypes.I32}
+
+	case "cast":
+		value := e.evaluate(call.Parameters[0])
+		if _, isError := value.(*Error); isError {
+			return value
+		}
+
+		to, ok := e.resolveType(call.TypeParameters[0])
+		if !ok {
+			return newError("can't cast to "+call.TypeParameters[0].String()+" at compile time", call)
+		}
+
+		return cast(call, value, to)
+	}
+
+	return newError("can't evaluate @"+call.Name+" at compile time", call)
+}
+
+func (e *evaluator) resolveType(t ctypes.Type) (ctypes.Type, bool) {
+	if _, isAnonymous := t.(*ctypes.Anonymous); isAnonymous {
+		return e.environment.Type(t)
+	}
+
+	return t, t != nil
+}
+
+// cast converts numbers like the compiler does at runtime
+func cast(call *ast.BuiltinCall, value Value, to ctypes.Type) Value {
+	switch value := value.(type) {
+	case *Integer:
+		if ctypes.IsInteger(to) {
+			return wrap(value.Value, to)
+		}
+
+		if ctypes.IsFloat(to) {
+			if isUnsigned(value.Type) {
+				return newFloat(float64(uint64(value.Value)), to)
+			}
+
+			return newFloat(float64(value.Value), to)
+		}
+
+	case *Float:
+		if ctypes.IsFloat(to) {
+			return newFloat(value.Value, to)
+		}
+
+		if ctypes.IsInteger(to) {
+			truncated := math.Trunc(value.Value)
+			size := bitSize(to)
+			if isUnsigned(to) {
+				if !(truncated >= 0 && truncated < math.Ldexp(1, int(size))) {
+					return newError(fmt.Sprintf("%v doesn't fit in %s", value.Value, to), call)
+				}
+
+				return wrap(int64(uint64(truncated)), to)
+			}
+
+			limit := math.Ldexp(1, int(size)-1)
+			if !(truncated >= -limit && truncated < limit) {
+				return newError(fmt.Sprintf("%v doesn't fit in %s", value.Value, to), call)
+			}
+
+			return wrap(int64(truncated), to)
+		}
+
+	case *String:
+		if pointer, isPointer := to.(*ctypes.Pointer); isPointer && pointer.Inner.String() == ctypes.I8.String() {
+			return value
+		}
+	}
+
+	return newError("can't evaluate "+describe(call)+" at compile time", call)
 }
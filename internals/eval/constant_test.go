@@ -0,0 +1,210 @@
+package eval_test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gabivlj/candice/internals/ast"
+	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/eval"
+	"github.com/gabivlj/candice/internals/lexer"
+	"github.com/gabivlj/candice/internals/parser"
+	"github.com/gabivlj/candice/pkg/a"
+)
+
+type environment struct {
+	functions map[string]ast.Statement
+}
+
+func (environment) Constant(identifier *ast.Identifier) (eval.Value, bool) {
+	return eval.Symbol(identifier.Token.Literal)
+}
+
+func (environment) Type(t ctypes.Type) (ctypes.Type, bool) {
+	return nil, false
+}
+
+func (e environment) Function(callee ast.Expression) (*ast.FunctionDeclarationStatement, eval.Environment, error) {
+	name := callee.(*ast.Identifier).Token.Literal
+	function, err := eval.ComptimeFunction(name, e.functions[name])
+	return function, e, err
+}
+
+func format(value eval.Value) string {
+	switch value := value.(type) {
+	case *eval.Integer:
+		if ctypes.IsUnsignedInteger(value.Type) {
+			return fmt.Sprintf("%d %s", uint64(value.Value), value.Type)
+		}
+
+		return fmt.Sprintf("%d %s", value.Value, value.Type)
+	case *eval.Float:
+		return fmt.Sprintf("%v %s", value.Value, value.Type)
+	case *eval.String:
+		return strconv.Quote(value.Value)
+	case *eval.Array:
+		elements := make([]string, len(value.Elements))
+		for i, element := range value.Elements {
+			elements[i] = format(element)
+		}
+
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *eval.Error:
+		return value.Message
+	}
+
+	return ""
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{`3 + 4 * 2`, "11 i32"},
+		{`200 as u8 + 100 as u8`, "44 u8"},
+		{`127 as i8 + 1 as i8`, "-128 i8"},
+		{`0 as u64 - 1 as u64`, "18446744073709551615 u64"},
+		{`-8 >> 1`, "2147483644 i32"},
+		{`-7 / 2`, "-3 i32"},
+		{`255 as u8 as i32`, "255 i32"},
+		{`-1 as i8 as i32`, "-1 i32"},
+		{`1.5 / 3.0`, "0.5 f32"},
+		{`7.9 as i32`, "7 i32"},
+		{`3 as f64 * 0.5 as f64`, "1.5 f64"},
+		{`@sizeof([4]i64) + 1`, "33 i32"},
+		{`3 > 2 && "a" == "a"`, "1 i1"},
+		{`!(1 == 1) || 0`, "0 i1"},
+		{`"hello"`, `"hello"`},
+	}
+
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.expression))
+		program := p.Parse()
+		a.Assert(len(p.Errors) == 0, p.Errors)
+		value := eval.Evaluate(program.Statements[0].(*ast.ExpressionStatement).Expression, environment{})
+		a.AssertEqual(format(value), test.expected)
+	}
+
+	for _, expression := range []string{`1 / 0`, `1 << 32`, `1 + 1 as i64`, `"a" < "b"`, `300.0 as u8`, `&3`} {
+		p := parser.New(lexer.New(expression))
+		program := p.Parse()
+		value := eval.Evaluate(program.Statements[0].(*ast.ExpressionStatement).Expression, environment{})
+		_, isError := value.(*eval.Error)
+		a.Assert(isError, expression)
+	}
+}
+
+const comptimeFunctions = `
+extern func puts(*i8) i32;
+
+comptime func factorial(n i32) i32 {
+	if n <= 1 {
+		return 1
+	}
+
+	return n * factorial(n - 1)
+}
+
+comptime func crc(n u32) u32 {
+	for i := 0; i < 8; ++i {
+		if n & 1 as u32 == 1 as u32 {
+			n = n >> 1 ^ 0xEDB88320 as u32
+		} else {
+			n = n >> 1
+		}
+	}
+
+	return n
+}
+
+comptime func squares() [4]i32 {
+	table := [4]i32{}
+	for i := 0; i < 4; ++i {
+		table[i] = i * i
+	}
+
+	copied := table
+	copied[0] = 100
+	return table
+}
+
+comptime func length(s *i8) i32 {
+	i := 0
+	for s[i] != 0 as i8 {
+		++i
+	}
+
+	return i
+}
+
+comptime func forever() i32 {
+	for {
+	}
+
+	return 0
+}
+
+comptime func outOfRange() i32 {
+	table := [2]i32{1, 2}
+	return table[2]
+}
+
+func runtime() i32 {
+	return 0
+}
+
+comptime func callsExtern() i32 {
+	return puts("hello")
+}
+`
+
+func TestEvaluate_Comptime(t *testing.T) {
+	p := parser.New(lexer.New(comptimeFunctions))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+	env := environment{functions: map[string]ast.Statement{}}
+	for _, statement := range program.Statements {
+		switch statement := statement.(type) {
+		case *ast.FunctionDeclarationStatement:
+			env.functions[ast.RetrieveID(statement.FunctionType.Name)] = statement
+		case *ast.ExternStatement:
+			env.functions[ast.RetrieveID(statement.Type.(*ctypes.Function).Name)] = statement
+		}
+	}
+
+	evaluate := func(expression string) eval.Value {
+		p := parser.New(lexer.New(expression))
+		program := p.Parse()
+		a.Assert(len(p.Errors) == 0, p.Errors)
+		return eval.Evaluate(program.Statements[0].(*ast.ExpressionStatement).Expression, env)
+	}
+
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{`factorial(10)`, "3628800 i32"},
+		{`crc(1 as u32)`, "1996959894 u32"},
+		{`crc(255 as u32)`, "755167117 u32"},
+		{`squares()`, "[0 i32, 1 i32, 4 i32, 9 i32]"},
+		{`squares()[3] + 1`, "10 i32"},
+		{`length("hello")`, "5 i32"},
+		{`outOfRange()`, "outOfRange() failed (53:14): index 2 is out of range on table[2], its length is 2"},
+		{`runtime()`, "runtime isn't a comptime function, declare it with 'comptime func' to call it at compile time"},
+		{`callsExtern()`, "callsExtern() failed (61:13): puts is an extern function, extern functions can't be called at compile time"},
+		{`factorial()`, "factorial expects 1 parameters, got 0"},
+	}
+
+	for _, test := range tests {
+		a.AssertEqual(format(evaluate(test.expression)), test.expected)
+	}
+
+	stepLimit := eval.StepLimit
+	eval.StepLimit = 1000
+	defer func() { eval.StepLimit = stepLimit }()
+	_, isError := evaluate(`forever()`).(*eval.Error)
+	a.Assert(isError, "forever() should stop")
+}
@@ -0,0 +1,558 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/gabivlj/candice/internals/ast"
+	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/ops"
+)
+
+// StepLimit is the number of expressions and statements that evaluating a single constant can run,
+// it stops comptime functions that never return.
+var StepLimit = 10_000_000
+
+// maxDepth is how many comptime calls can be nested
+const maxDepth = 1000
+
+// control tells the statement that is running what to do after one of its children runs
+type control int
+
+const (
+	next control = iota
+	breaks
+	continues
+	returns
+	fails
+)
+
+// ComptimeFunction checks that the statement that declares the function called name can be
+// called at compile time, environments use it so every phase reports the same errors.
+func ComptimeFunction(name string, statement ast.Statement) (*ast.FunctionDeclarationStatement, error) {
+	switch statement := statement.(type) {
+	case *ast.FunctionDeclarationStatement:
+		if !statement.FunctionType.Comptime {
+			return nil, fmt.Errorf("%s isn't a comptime function, declare it with 'comptime func' to call it at compile time", name)
+		}
+
+		return statement, nil
+	case *ast.ExternStatement:
+		return nil, fmt.Errorf("%s is an extern function, extern functions can't be called at compile time", name)
+	}
+
+	return nil, fmt.Errorf("unknown function %s", name)
+}
+
+func (e *evaluator) step(node ast.Node) *Error {
+	*e.steps++
+	if *e.steps > StepLimit {
+		return &Error{
+			Message: fmt.Sprintf("the evaluation didn't finish after %d steps, is there an infinite loop?", StepLimit),
+			Token:   node.GetToken(),
+		}
+	}
+
+	return nil
+}
+
+func (e *evaluator) variable(name string) *Value {
+	for i := len(e.scopes) - 1; i >= 0; i-- {
+		if variable, ok := e.scopes[i][name]; ok {
+			return variable
+		}
+	}
+
+	return nil
+}
+
+func (e *evaluator) declare(name string, value Value) {
+	e.scopes[len(e.scopes)-1][name] = &value
+}
+
+func (e *evaluator) enterScope() {
+	e.scopes = append(e.scopes, map[string]*Value{})
+}
+
+func (e *evaluator) leaveScope() {
+	e.scopes = e.scopes[:len(e.scopes)-1]
+}
+
+// call runs a comptime function, it returns nil if the function doesn't return a value
+func (e *evaluator) call(call *ast.Call) Value {
+	function, environment, err := e.environment.Function(call.Left)
+	if err != nil {
+		return newError(err.Error(), call)
+	}
+
+	if e.depth >= maxDepth {
+		return newError(fmt.Sprintf("comptime calls can't be nested more than %d times", maxDepth), call)
+	}
+
+	functionType := function.FunctionType
+	if len(call.Parameters) != len(functionType.Parameters) {
+		return newError(fmt.Sprintf("%s expects %d parameters, got %d", call.Left, len(functionType.Parameters), len(call.Parameters)), call)
+	}
+
+	callee := &evaluator{
+		environment: environment,
+		scopes:      []map[string]*Value{{}},
+		steps:       e.steps,
+		depth:       e.depth + 1,
+	}
+
+	for i, parameter := range call.Parameters {
+		value := e.evaluate(parameter)
+		if _, isError := value.(*Error); isError {
+			return value
+		}
+
+		callee.declare(functionType.Names[i], callee.convert(value, functionType.Parameters[i]))
+	}
+
+	control, value := callee.execute(function.Block)
+	if control == fails {
+		// errors inside of the function point to its body, the outermost call says which call failed
+		err := value.(*Error)
+		if e.depth > 0 {
+			return err
+		}
+
+		return newError(fmt.Sprintf("%s failed (%d:%d): %s", call, err.Token.Line, err.Token.Position, err.Message), call)
+	}
+
+	if control != returns || value == nil {
+		if functionType.Return != nil && functionType.Return != ctypes.VoidType {
+			return newError(fmt.Sprintf("%s finished without returning a value", call), call)
+		}
+
+		return nil
+	}
+
+	if _, isList := functionType.Return.(*ctypes.TypeList); isList {
+		return newError(fmt.Sprintf("%s returns multiple values, comptime functions can only return one", call), call)
+	}
+
+	return callee.convert(value, functionType.Return)
+}
+
+// convert gives the value the type of the variable, parameter or element where it's stored,
+// arrays are copied so they don't share their elements.
+func (e *evaluator) convert(value Value, t ctypes.Type) Value {
+	t, ok := e.resolveType(t)
+	if !ok || t == ctypes.TODO() {
+		return copyValue(value)
+	}
+
+	switch value := value.(type) {
+	case *Integer:
+		if ctypes.IsInteger(t) {
+			return wrap(value.Value, t)
+		}
+	case *Float:
+		if ctypes.IsFloat(t) {
+			return newFloat(value.Value, t)
+		}
+	}
+
+	return copyValue(value)
+}
+
+func typeOf(value Value) ctypes.Type {
+	switch value := value.(type) {
+	case *Integer:
+		return value.Type
+	case *Float:
+		return value.Type
+	case *Array:
+		return value.Type
+	}
+
+	return nil
+}
+
+func (e *evaluator) zeroValue(t ctypes.Type, expression ast.Expression) Value {
+	if err := e.step(expression); err != nil {
+		return err
+	}
+
+	resolved, ok := e.resolveType(t)
+	if ok {
+		switch {
+		case ctypes.IsInteger(resolved):
+			return wrap(0, resolved)
+		case ctypes.IsFloat(resolved):
+			return newFloat(0, resolved)
+		}
+
+		if array, isArray := resolved.(*ctypes.Array); isArray {
+			elements := make([]Value, array.Length)
+			for i := range elements {
+				elements[i] = e.zeroValue(array.Inner, expression)
+				if _, isError := elements[i].(*Error); isError {
+					return elements[i]
+				}
+			}
+
+			return &Array{Elements: elements, Type: array}
+		}
+	}
+
+	return newError("can't create an empty "+t.String()+" at compile time", expression)
+}
+
+func (e *evaluator) evaluateArrayLiteral(literal *ast.ArrayLiteral) Value {
+	t, _ := e.resolveType(literal.Type)
+	array, isArray := t.(*ctypes.Array)
+	if !isArray {
+		return newError("can't evaluate "+describe(literal)+" at compile time", literal)
+	}
+
+	if int64(len(literal.Values)) > array.Length {
+		return newError(fmt.Sprintf("%s has %d values but its length is %d", literal, len(literal.Values), array.Length), literal)
+	}
+
+	elements := make([]Value, array.Length)
+	for i := range elements {
+		if i >= len(literal.Values) {
+			elements[i] = e.zeroValue(array.Inner, literal)
+		} else {
+			elements[i] = e.convert(e.evaluate(literal.Values[i]), array.Inner)
+		}
+
+		if _, isError := elements[i].(*Error); isError {
+			return elements[i]
+		}
+	}
+
+	return &Array{Elements: elements, Type: array}
+}
+
+func (e *evaluator) evaluateIndexAccess(access *ast.IndexAccess) Value {
+	left := e.evaluate(access.Left)
+	if _, isError := left.(*Error); isError {
+		return left
+	}
+
+	switch left := left.(type) {
+	case *Array:
+		index, err := e.index(access, len(left.Elements))
+		if err != nil {
+			return err
+		}
+
+		return left.Elements[index]
+	case *String:
+		// strings end with a NUL character like at runtime
+		index, err := e.index(access, len(left.Value)+1)
+		if err != nil {
+			return err
+		}
+
+		if index == len(left.Value) {
+			return &Integer{Value: 0, Type: ctypes.I8}
+		}
+
+		return wrap(int64(left.Value[index]), ctypes.I8)
+	}
+
+	return newError("can't index "+describe(access.Left)+" at compile time", access)
+}
+
+func (e *evaluator) index(access *ast.IndexAccess, length int) (int, *Error) {
+	value := e.evaluate(access.Access)
+	if err, isError := value.(*Error); isError {
+		return 0, err
+	}
+
+	integer, isInteger := value.(*Integer)
+	if !isInteger {
+		return 0, newError("the index of "+describe(access)+" must be an integer", access)
+	}
+
+	if integer.Value < 0 || integer.Value >= int64(length) {
+		index := fmt.Sprint(integer.Value)
+		if isUnsigned(integer.Type) {
+			index = fmt.Sprint(uint64(integer.Value))
+		}
+
+		return 0, newError(fmt.Sprintf("index %s is out of range on %s, its length is %d", index, access, length), access)
+	}
+
+	return int(integer.Value), nil
+}
+
+// slot returns where the value of a variable or array element is stored so it can be assigned
+func (e *evaluator) slot(expression ast.Expression) (*Value, *Error) {
+	switch expression := expression.(type) {
+	case *ast.Identifier:
+		if variable := e.variable(expression.Name); variable != nil {
+			return variable, nil
+		}
+
+		return nil, newError("can't assign "+expression.Token.Literal+" at compile time, only the variables of comptime functions can be assigned", expression)
+
+	case *ast.IndexAccess:
+		slot, err := e.slot(expression.Left)
+		if err != nil {
+			return nil, err
+		}
+
+		array, isArray := (*slot).(*Array)
+		if !isArray {
+			return nil, newError("can't assign an element of "+describe(expression.Left)+" at compile time", expression)
+		}
+
+		index, err := e.index(expression, len(array.Elements))
+		if err != nil {
+			return nil, err
+		}
+
+		return &array.Elements[index], nil
+	}
+
+	return nil, newError("can't assign "+describe(expression)+" at compile time", expression)
+}
+
+func (e *evaluator) increment(prefix *ast.PrefixOperation) Value {
+	slot, err := e.slot(prefix.Right)
+	if err != nil {
+		return err
+	}
+
+	delta := int64(1)
+	if prefix.Operation == ops.SubtractOne {
+		delta = -1
+	}
+
+	switch value := (*slot).(type) {
+	case *Integer:
+		*slot = wrap(value.Value+delta, value.Type)
+	case *Float:
+		*slot = newFloat(value.Value+float64(delta), value.Type)
+	default:
+		return newError("can't evaluate expression "+describe(prefix)+" at compile time", prefix)
+	}
+
+	return *slot
+}
+
+func equal(left, right Value) bool {
+	switch left := left.(type) {
+	case *Integer:
+		right, ok := right.(*Integer)
+		return ok && left.Value == right.Value
+	case *Float:
+		right, ok := right.(*Float)
+		return ok && left.Value == right.Value
+	case *String:
+		right, ok := right.(*String)
+		return ok && left.Value == right.Value
+	}
+
+	return false
+}
+
+// condition evaluates the condition of an if, for or switch
+func (e *evaluator) condition(expression ast.Expression) (bool, *Error) {
+	value := e.evaluate(expression)
+	if err, isError := value.(*Error); isError {
+		return false, err
+	}
+
+	return value.IsTruthy(), nil
+}
+
+func (e *evaluator) executeBlock(block *ast.Block) (control, Value) {
+	e.enterScope()
+	defer e.leaveScope()
+	return e.executeStatements(block.Statements)
+}
+
+func (e *evaluator) executeStatements(statements []ast.Statement) (control, Value) {
+	for _, statement := range statements {
+		if control, value := e.execute(statement); control != next {
+			return control, value
+		}
+	}
+
+	return next, nil
+}
+
+// execute runs a statement of a comptime function, the value is the returned value or the error
+func (e *evaluator) execute(statement ast.Statement) (control, Value) {
+	if err := e.step(statement); err != nil {
+		return fails, err
+	}
+
+	switch statement := statement.(type) {
+	case *ast.Block:
+		return e.executeBlock(statement)
+
+	case *ast.MacroBlock:
+		// the statements of the chosen #if branch belong to the enclosing scope
+		return e.executeStatements(statement.Statements)
+
+	case *ast.ExpressionStatement:
+		var value Value
+		if call, isCall := statement.Expression.(*ast.Call); isCall {
+			value = e.call(call)
+		} else {
+			value = e.evaluate(statement.Expression)
+		}
+
+		if _, isError := value.(*Error); isError {
+			return fails, value
+		}
+
+		return next, nil
+
+	case *ast.DeclarationStatement:
+		value := e.evaluate(statement.Expression)
+		if _, isError := value.(*Error); isError {
+			return fails, value
+		}
+
+		e.declare(statement.Name, e.convert(value, statement.Type))
+		return next, nil
+
+	case *ast.AssignmentStatement:
+		value := e.evaluate(statement.Expression)
+		if _, isError := value.(*Error); isError {
+			return fails, value
+		}
+
+		slot, err := e.slot(statement.Left)
+		if err != nil {
+			return fails, err
+		}
+
+		*slot = e.convert(value, typeOf(*slot))
+		return next, nil
+
+	case *ast.IfStatement:
+		return e.executeIf(statement)
+
+	case *ast.ForStatement:
+		return e.executeFor(statement)
+
+	case *ast.SwitchStatement:
+		return e.executeSwitch(statement)
+
+	case *ast.ReturnStatement:
+		if statement.Expression == nil {
+			return returns, nil
+		}
+
+		value := e.evaluate(statement.Expression)
+		if _, isError := value.(*Error); isError {
+			return fails, value
+		}
+
+		return returns, value
+
+	case *ast.BreakStatement:
+		return breaks, nil
+
+	case *ast.ContinueStatement:
+		return continues, nil
+	}
+
+	return fails, &Error{Message: "can't run " + describe(statement) + " at compile time", Token: statement.GetToken()}
+}
+
+func (e *evaluator) executeIf(statement *ast.IfStatement) (control, Value) {
+	isTrue, err := e.condition(statement.Condition)
+	if err != nil {
+		return fails, err
+	}
+
+	if isTrue {
+		return e.executeBlock(statement.Block)
+	}
+
+	for _, elseIf := range statement.ElseIfs {
+		isTrue, err := e.condition(elseIf.Condition)
+		if err != nil {
+			return fails, err
+		}
+
+		if isTrue {
+			return e.executeBlock(elseIf.Block)
+		}
+	}
+
+	if statement.Else != nil {
+		return e.executeBlock(statement.Else)
+	}
+
+	return next, nil
+}
+
+func (e *evaluator) executeFor(statement *ast.ForStatement) (control, Value) {
+	// the variables of the initializer only exist inside of the loop
+	e.enterScope()
+	defer e.leaveScope()
+
+	if statement.InitializerStatement != nil {
+		if control, value := e.execute(statement.InitializerStatement); control == fails {
+			return control, value
+		}
+	}
+
+	for {
+		// a loop without a condition and statements doesn't evaluate anything else
+		if err := e.step(statement); err != nil {
+			return fails, err
+		}
+
+		if statement.Condition != nil {
+			isTrue, err := e.condition(statement.Condition)
+			if err != nil {
+				return fails, err
+			}
+
+			if !isTrue {
+				return next, nil
+			}
+		}
+
+		control, value := e.executeBlock(statement.Block)
+		if control == fails || control == returns {
+			return control, value
+		}
+
+		if control == breaks {
+			return next, nil
+		}
+
+		if statement.Operation != nil {
+			if control, value := e.execute(statement.Operation); control == fails {
+				return control, value
+			}
+		}
+	}
+}
+
+func (e *evaluator) executeSwitch(statement *ast.SwitchStatement) (control, Value) {
+	value := e.evaluate(statement.Condition)
+	if _, isError := value.(*Error); isError {
+		return fails, value
+	}
+
+	for _, caseStatement := range statement.Cases {
+		caseValue := e.evaluate(caseStatement.Case)
+		if _, isError := caseValue.(*Error); isError {
+			return fails, caseValue
+		}
+
+		if equal(value, caseValue) {
+			return e.executeBlock(caseStatement.Block)
+		}
+	}
+
+	if statement.Default != nil {
+		return e.executeBlock(statement.Default)
+	}
+
+	return next, nil
+}
@@ -2,7 +2,10 @@ package eval
 
 import (
 	"runtime"
+	"sort"
+	"strconv"
 
+	"github.com/gabivlj/candice/internals/ctypes"
 	"github.com/gabivlj/candice/internals/token"
 )
 
@@ -11,8 +14,12 @@ type Value interface {
 	constantValue()
 }
 
+// Integer is an integer with the exact bit width of its type, booleans are integers of type i1.
+// Integers without type come from the defines and the platform symbols, they take the type of the
+// values they are operated with.
 type Integer struct {
 	Value int64
+	Type  ctypes.Type
 }
 
 func (i *Integer) constantValue() {}
@@ -22,7 +29,8 @@ func (i *Integer) IsTruthy() bool {
 }
 
 type Float struct {
-	Value int64
+	Value float64
+	Type  ctypes.Type
 }
 
 func (i *Float) constantValue() {}
@@ -31,6 +39,46 @@ func (i *Float) IsTruthy() bool {
 	return i.Value != 0
 }
 
+type String struct {
+	Value string
+}
+
+func (s *String) constantValue() {}
+
+func (s *String) IsTruthy() bool {
+	return s.Value != ""
+}
+
+// Array is the value of arrays created by comptime functions and array literals, it's copied
+// when it's stored so two variables never share the same elements.
+type Array struct {
+	Elements []Value
+	Type     ctypes.Type
+}
+
+func (a *Array) constantValue() {}
+
+func (a *Array) IsTruthy() bool {
+	return true
+}
+
+func (a *Array) copy() *Array {
+	elements := make([]Value, len(a.Elements))
+	for i, element := range a.Elements {
+		elements[i] = copyValue(element)
+	}
+
+	return &Array{Elements: elements, Type: a.Type}
+}
+
+func copyValue(value Value) Value {
+	if array, isArray := value.(*Array); isArray {
+		return array.copy()
+	}
+
+	return value
+}
+
 type Error struct {
 	Message string
 	Token   token.Token
@@ -78,3 +126,50 @@ var constants = map[string]Value{
 		Value: boolToInteger(runtime.GOARCH == "386"),
 	},
 }
+
+// defines are the symbols defined by the user, they take precedence over the predefined ones
+var defines = map[string]Value{}
+
+// SetDefines replaces the symbols defined by the user. true and false are defined as 1 and 0,
+// integers as integers and the rest as strings, an empty value defines the symbol as 1.
+func SetDefines(symbols map[string]string) {
+	defines = make(map[string]Value, len(symbols))
+	for name, value := range symbols {
+		if value == "" || value == "true" {
+			defines[name] = &Integer{Value: 1}
+		} else if value == "false" {
+			defines[name] = &Integer{Value: 0}
+		} else if integer, err := strconv.ParseInt(value, 0, 64); err == nil {
+			defines[name] = &Integer{Value: integer}
+		} else {
+			defines[name] = &String{Value: value}
+		}
+	}
+}
+
+// DefinesKey returns the symbols defined by the user in a stable order, two builds with the same
+// key parse every #if the same way.
+func DefinesKey() []string {
+	key := make([]string, 0, len(defines))
+	for name, value := range defines {
+		switch value := value.(type) {
+		case *Integer:
+			key = append(key, name+"="+strconv.FormatInt(value.Value, 10))
+		case *String:
+			key = append(key, name+"="+strconv.Quote(value.Value))
+		}
+	}
+
+	sort.Strings(key)
+	return key
+}
+
+// Symbol returns the value of a symbol defined by the user or by the platform
+func Symbol(name string) (Value, bool) {
+	if value, ok := defines[name]; ok {
+		return value, true
+	}
+
+	value, ok := constants[name]
+	return value, ok
+}
@@ -1,6 +1,9 @@
 package lexer
 
 import (
+	"strings"
+	"unicode/utf8"
+
 	"github.com/gabivlj/candice/internals/token"
 )
 
@@ -11,16 +14,39 @@ type Lexer struct {
 	ch           byte // current char
 	line         uint32
 	column       uint32
+	file         token.FileID
+
+	comments []token.Comment
+	// docs has the doc comments of each token that follows them
+	docs        map[token.Token]string
+	pendingDocs []string
 }
 
 // New Returns a new Lexer
 func New(input string) *Lexer {
-	l := &Lexer{input: input, line: 1, column: 0}
+	l := &Lexer{input: input, line: 1, column: 1, docs: map[token.Token]string{}}
 	// Initialize to first char.
 	l.readChar()
 	return l
 }
 
+// NewFile returns a new Lexer of a file, its tokens point to the file so errors can show where they are
+func NewFile(name, input string) *Lexer {
+	l := New(input)
+	l.file = token.AddFile(name, input)
+	return l
+}
+
+// File returns the file of the tokens
+func (l *Lexer) File() token.FileID {
+	return l.file
+}
+
+// Underline returns the line of the span and marks the span below it
+func (l *Lexer) Underline(span token.Span) string {
+	return span.Underline(l.input)
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for isLetter(l.ch) || isDigit(l.ch) {
@@ -43,25 +69,82 @@ func (l *Lexer) skipWhiteSpace() {
 	}
 }
 
+// Comments returns the comments that have been read so far
+func (l *Lexer) Comments() []token.Comment {
+	return l.comments
+}
+
+// Doc returns the text of the /// comments that are right before the token
+func (l *Lexer) Doc(t token.Token) string {
+	return l.docs[t]
+}
+
+// skipComments skips the whitespace and the comments before the next token and stores the comments,
+// if a block comment isn't closed it returns it and false.
+func (l *Lexer) skipComments() (token.Comment, bool) {
+	l.skipWhiteSpace()
+	for l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*') {
+		comment := token.Comment{Line: l.line, Position: l.column - 1, OverallPosition: l.position}
+		if l.peekChar() == '*' {
+			if !l.skipBlockComment() {
+				return comment, false
+			}
+
+			comment.Literal = l.input[comment.OverallPosition:l.position]
+		} else {
+			l.skipUntilJL()
+			end := l.position
+			if end > len(l.input) {
+				end = len(l.input)
+			}
+
+			comment.Literal = strings.TrimRight(l.input[comment.OverallPosition:end], "\r\n")
+			comment.Doc = strings.HasPrefix(comment.Literal, "///") && !strings.HasPrefix(comment.Literal, "////")
+		}
+
+		if comment.Doc {
+			l.pendingDocs = append(l.pendingDocs, strings.TrimPrefix(strings.TrimPrefix(comment.Literal, "///"), " "))
+		}
+
+		l.comments = append(l.comments, comment)
+		l.skipWhiteSpace()
+	}
+
+	return token.Comment{}, true
+}
+
+// skipBlockComment skips a /* */ comment, block comments can be nested
+func (l *Lexer) skipBlockComment() bool {
+	depth := 0
+	for l.ch != 0 {
+		if l.ch == '/' && l.peekChar() == '*' {
+			depth++
+			l.readChar()
+		} else if l.ch == '*' && l.peekChar() == '/' {
+			depth--
+			l.readChar()
+			if depth == 0 {
+				l.readChar()
+				return true
+			}
+		} else if l.ch == '\n' {
+			l.line++
+			l.column = 1
+		}
+
+		l.readChar()
+	}
+
+	return false
+}
+
 func (l *Lexer) skipUntilJL() {
 	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
-	l.readChar()
 	l.line++
 	l.column = 1
-}
-
-func (l *Lexer) RetrieveLine(t token.Token) string {
-	currentColumn := t.OverallPosition - 1
-	if t.Type == token.EOF {
-		t.OverallPosition = len(l.input) - 1
-		currentColumn = len(l.input) - 2
-	}
-	for currentColumn >= 0 && l.input[currentColumn] != '\n' && l.input[currentColumn] != '\t' {
-		currentColumn--
-	}
-	return l.input[currentColumn+1 : t.OverallPosition+1]
+	l.readChar()
 }
 
 // Returns if there is a combination with the next char, else returns otherwise param
@@ -86,34 +169,108 @@ func (l *Lexer) readTwoBytesToken(t token.TypeToken, peek byte) token.Token {
 }
 
 func (l *Lexer) readCharLiteral() token.Token {
+	start := l.newToken(token.CHAR, l.ch)
 	l.readChar()
-	var literal byte
-	if l.peekChar() == '\\' {
-		// it's a \n or \t
-		l.readChar()
-		c := l.ch
-		l.readChar()
-		if c == 'n' {
-			c = '\n'
-		} else if c == 't' {
-			c = '\t'
-		} else if c == 'r' {
-			c = '\r'
+	if l.ch == '\'' || l.ch == 0 {
+		return l.newToken(token.ILLEGAL, l.ch)
+	}
+
+	literal := string(l.ch)
+	if l.ch == '\\' {
+		escaped, ok := l.readEscape()
+		if !ok {
+			return l.newToken(token.ILLEGAL, l.ch)
 		}
-		literal = c
+
+		literal = escaped
 	} else {
-		literal = l.ch
 		l.readChar()
 	}
 
-	if l.ch != '\'' {
+	// chars are a single byte, so something like '\u{e9}' doesn't fit in one
+	if l.ch != '\'' || len(literal) != 1 {
 		return l.newToken(token.ILLEGAL, l.ch)
 	}
 
 	// Skip '
 	l.readChar()
 
-	return l.newToken(token.CHAR, literal)
+	start.Literal = literal
+	return start
+}
+
+// readEscape decodes the escape sequence that starts in the current '\\' and leaves the lexer
+// in the character after it. It returns false if the sequence is malformed.
+func (l *Lexer) readEscape() (string, bool) {
+	// Skip \
+	l.readChar()
+	c := l.ch
+	l.readChar()
+	switch c {
+	case 'n':
+		return "\n", true
+	case 't':
+		return "\t", true
+	case 'r':
+		return "\r", true
+	case '0':
+		return "\x00", true
+	case 'x':
+		value := 0
+		for i := 0; i < 2; i++ {
+			digit, ok := hexValue(l.ch)
+			if !ok {
+				return "", false
+			}
+
+			value = value*16 + digit
+			l.readChar()
+		}
+
+		return string([]byte{byte(value)}), true
+	case 'u':
+		if l.ch != '{' {
+			return "", false
+		}
+
+		l.readChar()
+		value, digits := 0, 0
+		for l.ch != '}' {
+			digit, ok := hexValue(l.ch)
+			if !ok || digits == 6 {
+				return "", false
+			}
+
+			value = value*16 + digit
+			digits++
+			l.readChar()
+		}
+
+		// Skip }
+		l.readChar()
+		if digits == 0 || value > utf8.MaxRune || (value >= 0xD800 && value <= 0xDFFF) {
+			return "", false
+		}
+
+		return string(rune(value)), true
+	case '\\', '\'', '"':
+		return string(c), true
+	}
+
+	return "", false
+}
+
+func hexValue(ch byte) (int, bool) {
+	switch {
+	case isDigit(ch):
+		return int(ch - '0'), true
+	case ch >= 'a' && ch <= 'f':
+		return int(ch-'a') + 10, true
+	case ch >= 'A' && ch <= 'F':
+		return int(ch-'A') + 10, true
+	}
+
+	return 0, false
 }
 
 func (l *Lexer) getMacroToken() token.Token {
@@ -124,6 +281,10 @@ func (l *Lexer) getMacroToken() token.Token {
 	case "if":
 
 		return l.newToken(token.MACRO_IF, macro)
+	case "elif":
+		return l.newToken(token.MACRO_ELIF, macro)
+	case "else":
+		return l.newToken(token.MACRO_ELSE, macro)
 	}
 
 	return l.newToken(token.ILLEGAL, macro)
@@ -131,13 +292,28 @@ func (l *Lexer) getMacroToken() token.Token {
 
 // NextToken Returns the next token of an input
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-	l.skipWhiteSpace()
-	for l.ch == '/' && l.peekChar() == '/' {
-		l.skipUntilJL()
-		l.skipWhiteSpace()
+	if unclosed, ok := l.skipComments(); !ok {
+		return token.Token{
+			Type: token.ILLEGAL, Literal: "/*", Line: unclosed.Line, Position: unclosed.Position, OverallPosition: unclosed.OverallPosition,
+			EndLine: l.line, EndPosition: l.column - 1, File: l.file,
+		}
 	}
-	l.skipWhiteSpace()
+
+	line, position, overallPosition := l.line, l.column-1, l.position
+	tok := l.nextToken()
+	tok.Line, tok.Position, tok.OverallPosition = line, position, overallPosition
+	tok.EndLine, tok.EndPosition = l.line, l.column-1
+	tok.File = l.file
+	if len(l.pendingDocs) > 0 {
+		l.docs[tok] = strings.Join(l.pendingDocs, "\n")
+		l.pendingDocs = nil
+	}
+
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
+	var tok token.Token
 	switch l.ch {
 	case '\'':
 		tok = l.readCharLiteral()
@@ -156,11 +332,22 @@ func (l *Lexer) NextToken() token.Token {
 	case ']':
 		tok = l.newToken(token.RBRACKET, l.ch)
 	case '"':
+		if l.atTripleQuote() {
+			tok = l.readMultilineString()
+			break
+		}
+
+		literal, ok := l.readString()
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok.Literal = literal
 		tok.Line = l.line
 		tok.Position = l.column - uint32(len(tok.Literal))
 		tok.OverallPosition = l.position
+		if !ok {
+			tok.Type = token.ILLEGAL
+		}
+	case '`':
+		tok = l.readRawString()
 	case '=':
 		tok = l.peekerForTwoChars('=', l.newToken(token.ASSIGN, '='), token.EQ)
 	case '!':
@@ -242,31 +429,104 @@ func (l *Lexer) parseNumericToken() token.Token {
 	return tok
 }
 
-func (l *Lexer) readString() string {
-	s := ""
+// readString reads a "" string, it returns false if it has a malformed escape sequence
+func (l *Lexer) readString() (string, bool) {
+	s := strings.Builder{}
+	valid := true
 	l.readChar()
 	for l.ch != '"' && l.ch != 0 {
 		if l.ch == '\\' {
-			// It's a jumpline
-			if l.peekChar() == 'n' {
-				l.readChar()
-				l.readChar()
-				s += string('\n')
-				continue
-			}
+			escaped, ok := l.readEscape()
+			valid = valid && ok
+			s.WriteString(escaped)
+			continue
+		}
 
-			// Read it as a literal
-			l.readChar()
-			s += string(l.ch)
-			l.readChar()
+		// the input is utf8, so multibyte characters are copied byte by byte
+		s.WriteByte(l.ch)
+		l.readChar()
+	}
+
+	return s.String(), valid
+}
+
+// readMultilineString reads a """ """ string, which can span multiple lines and
+// handles escape sequences like a normal string. A line break right after the opening
+// quotes is not part of the string.
+func (l *Lexer) readMultilineString() token.Token {
+	tok := token.Token{Type: token.STRING, Line: l.line, Position: l.column - 1, OverallPosition: l.position}
+	// Skip the opening quotes
+	l.readChar()
+	l.readChar()
+	l.readChar()
+	if l.ch == '\r' && l.peekChar() == '\n' {
+		l.readChar()
+	}
+
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+		l.readChar()
+	}
+
+	s := strings.Builder{}
+	valid := true
+	for !l.atTripleQuote() {
+		if l.ch == 0 {
+			return token.Token{Type: token.ILLEGAL, Literal: `"""`, Line: tok.Line, Position: tok.Position, OverallPosition: tok.OverallPosition}
+		}
+
+		if l.ch == '\\' {
+			escaped, ok := l.readEscape()
+			valid = valid && ok
+			s.WriteString(escaped)
 			continue
 		}
 
-		s += string(l.ch)
+		if l.ch == '\n' {
+			l.line++
+			l.column = 1
+		}
+
+		s.WriteByte(l.ch)
+		l.readChar()
+	}
+
+	// Leave the lexer in the last quote
+	l.readChar()
+	l.readChar()
+	if !valid {
+		tok.Type = token.ILLEGAL
+	}
+
+	tok.Literal = s.String()
+	return tok
+}
+
+func (l *Lexer) atTripleQuote() bool {
+	return l.ch == '"' && l.peekChar() == '"' && l.readPosition+1 < len(l.input) && l.input[l.readPosition+1] == '"'
+}
+
+// readRawString reads a string between backticks, escape sequences are not processed and it can span multiple lines
+func (l *Lexer) readRawString() token.Token {
+	tok := token.Token{Type: token.STRING, Line: l.line, Position: l.column - 1, OverallPosition: l.position}
+	l.readChar()
+	position := l.position
+	for l.ch != '`' {
+		if l.ch == 0 {
+			return token.Token{Type: token.ILLEGAL, Literal: "`", Line: tok.Line, Position: tok.Position, OverallPosition: tok.OverallPosition}
+		}
+
+		if l.ch == '\n' {
+			l.line++
+			l.column = 1
+		}
+
 		l.readChar()
 	}
 
-	return s
+	tok.Literal = l.input[position:l.position]
+	return tok
 }
 
 func (l *Lexer) newToken(tokenType token.TypeToken, ch byte) token.Token {
@@ -277,6 +537,25 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isHexDigit(ch byte) bool {
+	_, ok := hexValue(ch)
+	return ok
+}
+
+// readDigits reads the digits that satisfy isValidDigit, they can be separated with '_' like in 1_000_000
+func (l *Lexer) readDigits(isValidDigit func(byte) bool) {
+	for isValidDigit(l.ch) || (l.ch == '_' && isValidDigit(l.peekChar())) {
+		l.readChar()
+	}
+}
+
+// readSuffix reads the type suffix of a number like in 10u8 or 1.5f32
+func (l *Lexer) readSuffix() {
+	if isLetter(l.ch) {
+		l.readIdentifier()
+	}
+}
+
 func (l *Lexer) readHex() (string, token.TypeToken) {
 	position := l.position
 	// '0'
@@ -284,22 +563,32 @@ func (l *Lexer) readHex() (string, token.TypeToken) {
 	// 'x'
 	l.readChar()
 	// Numbers and characters
-	for isDigit(l.ch) || (l.ch >= 'A' && l.ch <= 'F') || (l.ch >= 'a' && l.ch <= 'f') {
-		l.readChar()
-	}
+	l.readDigits(isHexDigit)
+	l.readSuffix()
 	return l.input[position:l.position], token.HEX
 }
 
+func (l *Lexer) readOctal() (string, token.TypeToken) {
+	position := l.position
+	// '0'
+	l.readChar()
+	// 'o'
+	l.readChar()
+	// every decimal digit is read so the parser can report the ones that aren't octal, like the 9 of 0o19
+	l.readDigits(isDigit)
+	l.readSuffix()
+	return l.input[position:l.position], token.OCTAL
+}
+
 func (l *Lexer) readBin() (string, token.TypeToken) {
 	position := l.position
 	// '0'
 	l.readChar()
 	// 'b'
 	l.readChar()
-	// Numbers and characters
-	for l.ch == '0' || l.ch == '1' {
-		l.readChar()
-	}
+	// Numbers, the parser reports the digits that aren't binary
+	l.readDigits(isDigit)
+	l.readSuffix()
 	return l.input[position:l.position], token.BINARY
 }
 
@@ -316,18 +605,19 @@ func (l *Lexer) readNumber() (string, token.TypeToken) {
 		return l.readBin()
 	}
 
-	for isDigit(l.ch) {
-		l.readChar()
+	if l.ch == '0' && l.peekChar() == 'o' {
+		return l.readOctal()
 	}
 
+	l.readDigits(isDigit)
+
 	if l.ch == '.' && l.peekChar() >= '0' && l.peekChar() <= '9' {
 		l.readChar()
 		tokenType = token.FLOAT
 	}
 
-	for isDigit(l.ch) {
-		l.readChar()
-	}
+	l.readDigits(isDigit)
+	l.readSuffix()
 	return l.input[position:l.position], tokenType
 }
 
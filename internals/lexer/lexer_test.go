@@ -27,3 +27,106 @@ func TestLexer_CharLiteral(t *testing.T) {
 	a.Assert(ch.Type == token.CHAR)
 	a.Assert(ch.Literal == "h")
 }
+
+func TestLexer_Escapes(t *testing.T) {
+	l := New(`'\n' '\'' '\\' '\0' '\x41' '\u{e9}'`)
+	for _, expected := range []string{"\n", "'", "\\", "\x00", "A"} {
+		ch := l.NextToken()
+		a.Assert(ch.Type == token.CHAR, ch)
+		a.AssertEqual(ch.Literal, expected)
+	}
+
+	a.Assert(l.NextToken().Type == token.ILLEGAL)
+
+	l = New(`"\t\"\x41\u{1F600}\0" "\xZZ"`)
+	str := l.NextToken()
+	a.Assert(str.Type == token.STRING, str)
+	a.AssertEqual(str.Literal, "\t\"A\U0001F600\x00")
+	a.Assert(l.NextToken().Type == token.ILLEGAL)
+
+	// unknown escapes are errors
+	for _, unknown := range []string{`"\q"`, `"""\q"""`, `'\q'`} {
+		a.Assert(New(unknown).NextToken().Type == token.ILLEGAL, unknown)
+	}
+}
+
+func TestLexer_Strings(t *testing.T) {
+	l := New("`raw\\n\n\"x\"` \"\"\"\nfirst\n  \"second\"\\t\"\"\" a")
+	raw := l.NextToken()
+	a.Assert(raw.Type == token.STRING, raw)
+	a.AssertEqual(raw.Literal, "raw\\n\n\"x\"")
+	multiline := l.NextToken()
+	a.Assert(multiline.Type == token.STRING && multiline.Line == 2, multiline)
+	a.AssertEqual(multiline.Literal, "first\n  \"second\"\t")
+	ident := l.NextToken()
+	a.Assert(ident.Type == token.IDENT && ident.Line == 4, ident)
+
+	// characters that take more than a byte are kept as they are
+	for _, str := range []string{`"héllo 😀"`, `"""héllo 😀"""`, "`héllo 😀`"} {
+		literal := New(str).NextToken()
+		a.Assert(literal.Type == token.STRING, literal)
+		a.AssertEqual(literal.Literal, "héllo 😀")
+	}
+
+	a.Assert(New("`unclosed").NextToken().Type == token.ILLEGAL)
+	a.Assert(New(`"""unclosed""`).NextToken().Type == token.ILLEGAL)
+}
+
+func TestLexer_Numbers(t *testing.T) {
+	l := New("1_000_000 0o17 10u8 1.5f32 0xFF_FFu16 0b1010_1010 1_ 3.0_1")
+	for _, expected := range []token.Token{
+		{Type: token.INT, Literal: "1_000_000"},
+		{Type: token.OCTAL, Literal: "0o17"},
+		{Type: token.INT, Literal: "10u8"},
+		{Type: token.FLOAT, Literal: "1.5f32"},
+		{Type: token.HEX, Literal: "0xFF_FFu16"},
+		{Type: token.BINARY, Literal: "0b1010_1010"},
+		{Type: token.INT, Literal: "1_"},
+		{Type: token.FLOAT, Literal: "3.0_1"},
+	} {
+		number := l.NextToken()
+		a.Assert(number.Type == expected.Type, number)
+		a.AssertEqual(number.Literal, expected.Literal)
+	}
+}
+
+func TestLexer_Comments(t *testing.T) {
+	l := New("/* a /* nested */ comment */ a // line\n/// doc\n/// more\nfunc")
+	ident := l.NextToken()
+	a.Assert(ident.Type == token.IDENT && ident.Literal == "a", ident)
+	function := l.NextToken()
+	a.Assert(function.Type == token.FUNCTION, function)
+	a.AssertEqual(l.Doc(function), "doc\nmore")
+	a.AssertEqual(l.Doc(ident), "")
+
+	comments := l.Comments()
+	a.Assert(len(comments) == 4, comments)
+	a.AssertEqual(comments[0].Literal, "/* a /* nested */ comment */")
+	a.AssertEqual(comments[1].Literal, "// line")
+	a.Assert(!comments[1].Doc && comments[2].Doc, comments)
+	a.Assert(comments[3].Line == 3, comments[3])
+
+	unclosed := New("a /* /* */")
+	unclosed.NextToken()
+	a.Assert(unclosed.NextToken().Type == token.ILLEGAL)
+}
+
+func TestLexer_Spans(t *testing.T) {
+	l := NewFile("spans.cd", "a := 10;\n// comment\nname == \"str\"")
+	for _, expected := range []token.Span{
+		{Line: 1, Position: 1, EndLine: 1, EndPosition: 2},
+		{Line: 1, Position: 3, EndLine: 1, EndPosition: 4},
+		{Line: 1, Position: 4, EndLine: 1, EndPosition: 5},
+		{Line: 1, Position: 6, EndLine: 1, EndPosition: 8},
+		{Line: 1, Position: 8, EndLine: 1, EndPosition: 9},
+		{Line: 3, Position: 1, EndLine: 3, EndPosition: 5},
+		{Line: 3, Position: 6, EndLine: 3, EndPosition: 8},
+		{Line: 3, Position: 9, EndLine: 3, EndPosition: 14},
+	} {
+		tok := l.NextToken()
+		expected.File = l.File()
+		a.Assert(tok.Span() == expected, tok, expected)
+	}
+
+	a.AssertEqual(l.File().Name(), "spans.cd")
+}
@@ -9,4 +9,36 @@ type Node struct {
 	Type ctypes.Type
 	// TODO This will be filled by the lexer
 	Token token.Token
+	// Range is the span of the source of the expression, the parser fills it
+	Range token.Span
+}
+
+// Span returns the range of the source of the node, nodes that the compiler creates don't have one
+func (n *Node) Span() token.Span {
+	if n == nil {
+		return token.Span{}
+	}
+
+	return n.Range
+}
+
+// SetSpan sets the range of the source of the node
+func (n *Node) SetSpan(span token.Span) {
+	n.Range = span
+}
+
+// Location is the span of the source that a statement covers, the parser fills it
+// once the whole statement has been parsed.
+type Location struct {
+	Range token.Span
+}
+
+// Span returns the range of the source of the node
+func (l *Location) Span() token.Span {
+	return l.Range
+}
+
+// SetSpan sets the range of the source of the node
+func (l *Location) SetSpan(span token.Span) {
+	l.Range = span
 }
@@ -1,8 +1,8 @@
 package parser
 
 import (
-	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -13,6 +13,7 @@ import (
 	"github.com/gabivlj/candice/internals/eval"
 	"github.com/gabivlj/candice/internals/lexer"
 	"github.com/gabivlj/candice/internals/node"
+	"github.com/gabivlj/candice/internals/ops"
 	"github.com/gabivlj/candice/internals/token"
 )
 
@@ -32,9 +33,41 @@ type Parser struct {
 	definedGenericTypes  map[string]ctypes.Type
 	currentTypeParameter int
 	currentProgram       *ast.Program
+	builtinFunctions     map[string]BuiltinFunctionParseRequirements
+
+	// constants has the values of the constant declarations of each scope
+	constants []map[string]eval.Value
+
+	// functions has the functions and extern functions that are declared, so comptime functions
+	// can be called by constants, array sizes and #if
+	functions map[string]ast.Statement
 
 	// Useful for error messages.
 	previousExpression ast.Expression
+
+	previousToken token.Token
+
+	// panicking is set after a syntax error until the parser skips to the next statement,
+	// the errors meanwhile are a consequence of the first one so they aren't reported
+	panicking bool
+
+	// depth is the number of blocks that are being parsed
+	depth int
+
+	// unwinding is set when the parser recovers on a top level declaration inside a block,
+	// which means that the block wasn't closed, so the blocks end there
+	unwinding bool
+
+	// negative is set when the integer literal that is being parsed is negated, like in -128i8
+	negative bool
+
+	// folded is set when the negation of an integer literal is already part of its value,
+	// like in -128i8, so the prefix is dropped
+	folded bool
+
+	// inactive is the number of #if branches that aren't chosen that are being parsed, they are
+	// only parsed syntactically because they may use symbols of other platforms or defines
+	inactive int
 }
 
 func (p *Parser) registerPrefixHandler(tokenType token.TypeToken, prefixFunc prefixFunc) {
@@ -46,23 +79,13 @@ func (p *Parser) registerInfixHandler(tokenType token.TypeToken, infixFunc infix
 }
 
 func (p *Parser) nextToken() token.Token {
-	prev := p.currentToken
+	p.previousToken = p.currentToken
 	p.currentToken, p.peekToken = p.peekToken, p.lexer.NextToken()
-	return prev
+	return p.previousToken
 }
 
 func (p *Parser) retrieveCurrentLineMessage() string {
-	blameLine := p.lexer.RetrieveLine(p.currentToken)
-	numberOfSpaces := len(blameLine) - len(p.currentToken.Literal)
-	if numberOfSpaces < 0 {
-		numberOfSpaces = 0
-	}
-	literalLen := len(p.currentToken.Literal)
-	if literalLen <= 0 {
-		literalLen = 1
-	}
-	c := fmt.Sprintf("\n%s\n%s happened here", blameLine, strings.Repeat(" ", numberOfSpaces)+strings.Repeat("^", literalLen))
-	return c
+	return fmt.Sprintf("\n%s happened here", p.lexer.Underline(p.currentToken.Span()))
 }
 
 func (p *Parser) expect(expected token.TypeToken) {
@@ -72,25 +95,16 @@ func (p *Parser) expect(expected token.TypeToken) {
 			return
 		}
 
-		p.addErrorMessage(fmt.Sprintf("expected a %s, received a '%s'\n%s", string(expected), p.currentToken.Literal, p.retrieveCurrentLineMessage()))
+		p.addErrorWithDetails(fmt.Sprintf("expected a %s, received a '%s'", string(expected), p.currentToken.Literal), p.retrieveCurrentLineMessage())
 	}
 }
 
 func (p *Parser) expectWithMessage(expected token.TypeToken, msg string) {
 	if p.currentToken.Type != expected {
-		p.addErrorMessage(fmt.Sprintf("unexpected token %s, received a '%s'\n%s", string(expected), p.currentToken.Literal, msg))
+		p.addErrorWithDetails(fmt.Sprintf("unexpected token %s, received a '%s'", string(expected), p.currentToken.Literal), msg)
 	}
 }
 
-func (p *Parser) addErrorMessage(message string) {
-	if len(p.Errors) >= 2 {
-		return
-	}
-	errMsg := errors.New(fmt.Sprintf("on %d:%d 'token: %s': %s\n%s",
-		p.currentToken.Line, p.currentToken.Position, p.currentToken.Literal, message, p.retrieveCurrentLineMessage()))
-	p.Errors = append(p.Errors, errMsg)
-}
-
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		lexer:               l,
@@ -99,6 +113,9 @@ func New(l *lexer.Lexer) *Parser {
 		Errors:              []error{},
 		ID:                  random.RandomString(10),
 		definedGenericTypes: map[string]ctypes.Type{},
+		builtinFunctions:    map[string]BuiltinFunctionParseRequirements{},
+		constants:           []map[string]eval.Value{{}},
+		functions:           map[string]ast.Statement{},
 	}
 
 	p.initBuiltinFunctions()
@@ -143,6 +160,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefixHandler(token.INT, p.parseInteger)
 	p.registerPrefixHandler(token.HEX, p.parseInteger)
 	p.registerPrefixHandler(token.BINARY, p.parseInteger)
+	p.registerPrefixHandler(token.OCTAL, p.parseInteger)
 	p.registerPrefixHandler(token.FLOAT, p.parseFloat)
 	p.registerPrefixHandler(token.LPAREN, p.parseParenthesisPrefix)
 	p.registerPrefixHandler(token.LBRACKET, p.parseStaticArray)
@@ -156,21 +174,112 @@ func (p *Parser) Parse() *ast.Program {
 	program := &ast.Program{Statements: []ast.Statement{}, ID: p.ID}
 	p.currentProgram = program
 	for p.currentToken.Type != token.EOF {
+		p.unwinding = false
 		program.Statements = append(program.Statements, p.parseStatement())
 	}
+	program.Comments = p.lexer.Comments()
 	return program
 }
 
+// parseStatement parses a statement, if it has a syntax error it returns an ast.BadStatement
+// and skips the tokens until the next statement.
 func (p *Parser) parseStatement() ast.Statement {
+	if p.panicking {
+		// it's part of a statement with an error, which is the one that recovers
+		return p.parseStatementByToken()
+	}
+
+	start := p.currentToken
+	statement := p.parseStatementByToken()
+	if !p.panicking {
+		p.setSpan(statement, start.Span())
+		return statement
+	}
+
+	p.synchronize(start)
+	bad := &ast.BadStatement{Token: start, To: p.currentToken}
+	p.setSpan(bad, start.Span())
+	return bad
+}
+
+// setSpan sets the span of the node from start to the last token that has been parsed
+func (p *Parser) setSpan(n ast.Node, start token.Span) {
+	spanned, ok := n.(interface{ SetSpan(token.Span) })
+	if !ok {
+		return
+	}
+
+	end := p.previousToken.Span()
+	if end.EndLine < start.Line || end.EndLine == start.Line && end.EndPosition <= start.Position {
+		end = start
+	}
+
+	spanned.SetSpan(start.To(end))
+}
+
+// synchronize skips the tokens of a statement with a syntax error, it stops after a ';' or a '}'
+// or before a '}' or a keyword that starts a statement. The parser always advances at least
+// a token so it can't get stuck in the same one.
+func (p *Parser) synchronize(start token.Token) {
+	p.panicking = false
+	for p.currentToken.Type != token.EOF {
+		advanced := p.currentToken.OverallPosition != start.OverallPosition || p.currentToken.Type != start.Type
+		if advanced && (p.previousToken.Type == token.SEMICOLON || p.previousToken.Type == token.RBRACE) {
+			break
+		}
+
+		if p.currentToken.Type == token.SEMICOLON {
+			p.nextToken()
+			break
+		}
+
+		if advanced && (p.currentToken.Type == token.RBRACE && p.depth > 0 || startsStatement(p.currentToken.Type)) {
+			p.unwinding = p.depth > 0 && startsDeclaration(p.currentToken.Type)
+			break
+		}
+
+		p.nextToken()
+	}
+
+	p.skipSemicolon()
+}
+
+// startsDeclaration returns true if the token starts a declaration that can only be top level
+func startsDeclaration(t token.TypeToken) bool {
+	switch t {
+	case token.FUNCTION, token.STRUCT, token.UNION, token.IMPORT, token.EXTERN, token.TYPE, token.PUBLIC, token.COMPTIME:
+		return true
+	}
+
+	return false
+}
+
+// startsStatement returns true if the token is a keyword that starts a statement
+func startsStatement(t token.TypeToken) bool {
+	switch t {
+	case token.IF, token.FOR, token.SWITCH, token.RETURN, token.BREAK, token.CONTINUE, token.CONST, token.MACRO_IF:
+		return true
+	}
+
+	return startsDeclaration(t)
+}
+
+func (p *Parser) parseStatementByToken() ast.Statement {
 	defer func() {
 		p.skipSemicolon()
 	}()
 
+	doc := p.lexer.Doc(p.currentToken)
+
 	switch p.currentToken.Type {
 	case token.SWITCH:
 		return p.parseSwitchStatement()
 	case token.MACRO_IF:
 		return p.parseMacroIf()
+	case token.MACRO_ELIF, token.MACRO_ELSE:
+		p.addErrorMessage("found " + strings.ToLower(string(p.currentToken.Type)) + " without a previous #if")
+		p.nextToken()
+		return &ast.MacroBlock{Block: &ast.Block{Statements: []ast.Statement{}}}
 	case token.EXTERN:
 		return p.parseExtern()
 	case token.IDENT:
@@ -182,11 +291,13 @@ func (p *Parser) parseStatement() ast.Statement {
 	case token.FOR:
 		return p.parseFor()
 	case token.STRUCT:
-		return p.parseStruct()
+		return p.withDoc(doc, p.parseStruct())
 	case token.UNION:
-		return p.parseUnion()
+		return p.withDoc(doc, p.parseUnion())
 	case token.FUNCTION:
-		return p.parseFunctionDeclaration()
+		return p.withDoc(doc, p.parseFunctionDeclaration())
+	case token.COMPTIME:
+		return p.withDoc(doc, p.parseComptimeFunction())
 	case token.RETURN:
 		return p.parseReturn()
 	case token.IMPORT:
@@ -200,7 +311,7 @@ func (p *Parser) parseStatement() ast.Statement {
 	case token.LBRACE:
 		return p.parseBlock()
 	case token.PUBLIC:
-		return p.parsePublicFunction()
+		return p.withDoc(doc, p.parsePublicFunction())
 	case token.CONST:
 		return p.parseDeclaration()
 	default:
@@ -210,6 +321,20 @@ func (p *Parser) parseStatement() ast.Statement {
 	}
 }
 
+// withDoc attaches the /// comments that were before the declaration
+func (p *Parser) withDoc(doc string, statement ast.Statement) ast.Statement {
+	switch statement := statement.(type) {
+	case *ast.FunctionDeclarationStatement:
+		statement.Doc = doc
+	case *ast.StructStatement:
+		statement.Doc = doc
+	case *ast.UnionStatement:
+		statement.Doc = doc
+	}
+
+	return statement
+}
+
 func (p *Parser) parseTypeDefinition(name token.Token) ast.Statement {
 	p.nextToken()
 	parsedType := p.parseType()
@@ -218,11 +343,25 @@ func (p *Parser) parseTypeDefinition(name token.Token) ast.Statement {
 
 func (p *Parser) parsePublicFunction() ast.Statement {
 	p.nextToken()
-	fn := p.parseFunctionDeclaration().(*ast.FunctionDeclarationStatement)
+	var fn *ast.FunctionDeclarationStatement
+	if p.currentToken.Type == token.COMPTIME {
+		fn = p.parseComptimeFunction().(*ast.FunctionDeclarationStatement)
+	} else {
+		fn = p.parseFunctionDeclaration().(*ast.FunctionDeclarationStatement)
+	}
+
 	fn.FunctionType.RedefineWithOriginalName = true
 	return fn
 }
 
+func (p *Parser) parseComptimeFunction() ast.Statement {
+	p.nextToken()
+	p.expect(token.FUNCTION)
+	fn := p.parseFunctionDeclaration().(*ast.FunctionDeclarationStatement)
+	fn.FunctionType.Comptime = true
+	return fn
+}
+
 func (p *Parser) parseGenericTypeDefinition() ast.Statement {
 	typeToken := p.nextToken()
 	p.expect(token.IDENT)
@@ -233,7 +372,8 @@ func (p *Parser) parseGenericTypeDefinition() ast.Statement {
 	}
 
 	if p.currentTypeParameter >= len(p.TypeParameters) {
-		p.Errors = append(p.Errors, fmt.Errorf("there are not enough type parameters passed to the file, we only got %d but it needs more", len(p.TypeParameters)))
+		message := fmt.Sprintf("there are not enough type parameters passed to the file, we only got %d but it needs more", len(p.TypeParameters))
+		p.Errors = append(p.Errors, &SyntaxError{Span: p.currentToken.Span(), Message: message, text: message})
 		return &ast.GenericTypeDefinition{}
 	}
 	t := p.TypeParameters[p.currentTypeParameter]
@@ -254,7 +394,7 @@ func (p *Parser) parseStaticArray() ast.Expression {
 	p.expect(token.LBRACE)
 	l := p.nextToken()
 	var expressions []ast.Expression
-	for p.currentToken.Type != token.RBRACE && p.currentToken.Type != token.EOF {
+	for p.currentToken.Type != token.RBRACE && p.currentToken.Type != token.EOF && !p.panicking {
 		if len(expressions) > 0 {
 			p.expect(token.COMMA)
 			p.nextToken()
@@ -306,6 +446,7 @@ func (p *Parser) parseImport() ast.Statement {
 		return &ast.ImportStatement{}
 	}
 
+	path.SetSpan(path.Token.Span())
 	return &ast.ImportStatement{
 		Name:  ast.CreateIdentifier(identifier.Literal, p.ID),
 		Types: types,
@@ -352,19 +493,25 @@ func (p *Parser) parseIdentifierExpression() ast.Expression {
 }
 
 func (p *Parser) parseAnonymousFunction() ast.Expression {
+	if p.peekToken.Type == token.IDENT {
+		// it's the declaration of the next function, probably the expression wasn't finished
+		p.addErrorMessage("expected an expression, found a function declaration")
+		return &ast.BadExpression{Node: &node.Node{Type: ctypes.TODO(), Token: p.currentToken}}
+	}
+
 	fun := p.nextToken()
 	var names []string
 	var types []ctypes.Type
 	p.expect(token.LPAREN)
 	p.nextToken()
-	for p.currentToken.Type != token.RPAREN && p.currentToken.Type != token.EOF {
+	for p.currentToken.Type != token.RPAREN && p.currentToken.Type != token.EOF && !p.panicking {
 		if len(names) > 0 {
 			p.expect(token.COMMA)
 			p.nextToken()
 		}
 		p.expect(token.IDENT)
 		ident := p.nextToken()
-		t := p.parseType()
+		t := p.parseTypeOf(ident, "parameter")
 		names = append(names, ast.CreateIdentifier(ident.Literal, p.ID))
 		types = append(types, t)
 	}
@@ -397,14 +544,14 @@ func (p *Parser) parseFunctionDeclaration() ast.Statement {
 	var types []ctypes.Type
 	p.expect(token.LPAREN)
 	p.nextToken()
-	for p.currentToken.Type != token.RPAREN && p.currentToken.Type != token.EOF {
+	for p.currentToken.Type != token.RPAREN && p.currentToken.Type != token.EOF && !p.panicking {
 		if len(names) > 0 {
 			p.expect(token.COMMA)
 			p.nextToken()
 		}
 		p.expect(token.IDENT)
 		ident := p.nextToken()
-		t := p.parseType()
+		t := p.parseTypeOf(ident, "parameter")
 		names = append(names, ast.CreateIdentifier(ident.Literal, p.ID))
 		types = append(types, t)
 	}
@@ -429,6 +576,7 @@ func (p *Parser) parseFunctionDeclaration() ast.Statement {
 		Block: block,
 	}
 
+	p.functions[name.Literal] = f
 	return f
 }
 
@@ -439,7 +587,7 @@ func (p *Parser) parseStructLiteral(module string) ast.Expression {
 	p.nextToken()
 	var structValues []ast.StructValue
 
-	for p.currentToken.Type != token.RBRACE && p.currentToken.Type != token.EOF {
+	for p.currentToken.Type != token.RBRACE && p.currentToken.Type != token.EOF && !p.panicking {
 		p.expect(token.IDENT)
 		identifier := p.nextToken()
 		p.expect(token.COLON)
@@ -478,11 +626,11 @@ func (p *Parser) parseIdTypePairs() ([]ctypes.Type, []string) {
 	var types []ctypes.Type
 	var names []string
 
-	for p.currentToken.Type != token.RBRACE && p.currentToken.Type != token.EOF {
+	for p.currentToken.Type != token.RBRACE && p.currentToken.Type != token.EOF && !p.panicking {
 		p.expect(token.IDENT)
 		name := p.nextToken()
 		names = append(names, name.Literal)
-		t := p.parseType()
+		t := p.parseTypeOf(name, "field")
 		types = append(types, t)
 
 	}
@@ -584,13 +732,31 @@ func (p *Parser) parseDeclaration() ast.Statement {
 	// pass assign
 	p.nextToken()
 
-	return &ast.DeclarationStatement{
+	declaration := &ast.DeclarationStatement{
 		Token:      id,
 		Name:       ast.CreateIdentifier(id.Literal, p.ID),
 		Type:       t,
 		Expression: p.parseExpression(0),
 		Constant:   isConstant,
 	}
+
+	if isConstant {
+		p.declareConstant(declaration)
+	}
+
+	return declaration
+}
+
+// parseTypeOf parses the type of a field or a parameter, it reports the name when the type is missing,
+// like in struct Point { x i32 y }
+func (p *Parser) parseTypeOf(name token.Token, what string) ctypes.Type {
+	t := p.parseType()
+	if t == nil {
+		p.addErrorAt(name, fmt.Sprintf("%s %s doesn't have a type", what, name.Literal))
+		return ctypes.TODO()
+	}
+
+	return t
 }
 
 // parseTypes is the same as parseType but it can return *ctypes.TypeList
@@ -640,6 +806,15 @@ func (p *Parser) parseType() ctypes.Type {
 		}
 		originalName := ast.RetrieveID(modules[0])
 
+		if length, isVector := ctypes.VectorLength(originalName); isVector {
+			inner := p.parseType()
+			if !ctypes.IsNumeric(inner) {
+				p.addErrorMessage("vectors can only contain integers, floats or booleans")
+			}
+
+			return &ctypes.Vector{Length: length, Inner: inner}
+		}
+
 		if t := ctypes.LiteralToType(originalName); t != nil {
 			return t
 		}
@@ -663,7 +838,7 @@ func (p *Parser) parseType() ctypes.Type {
 		p.nextToken()
 		var parameters []ctypes.Type
 		infiniteParameters := false
-		for p.currentToken.Type != token.RPAREN && p.currentToken.Type != token.EOF {
+		for p.currentToken.Type != token.RPAREN && p.currentToken.Type != token.EOF && !p.panicking {
 			if len(parameters) >= 1 {
 				p.expect(token.COMMA)
 				p.nextToken()
@@ -706,30 +881,55 @@ func (p *Parser) parseType() ctypes.Type {
 
 	if p.currentToken.Type == token.LBRACKET {
 		p.nextToken()
-		p.expect(token.INT)
-		integer, err := strconv.ParseInt(p.currentToken.Literal, 10, 32)
-		p.nextToken()
-		if err != nil {
-			p.addErrorMessage(fmt.Sprintf("couldn't parse array size because of the following expect: %s", err.Error()))
-		}
+		length := p.parseArrayLength()
 		p.expect(token.RBRACKET)
 		p.nextToken()
-		return &ctypes.Array{Length: integer, Inner: p.parseType()}
+		return &ctypes.Array{Length: length, Inner: p.parseType()}
 	}
 
 	return nil
 }
 
+// parseArrayLength parses the length of an array, which can be any constant expression
+// that evaluates to an integer, like [SIZE * 2]i32
+func (p *Parser) parseArrayLength() int64 {
+	expression := p.parseExpression(0)
+	if !p.canEvaluate(expression) {
+		return 0
+	}
+
+	value := p.evaluate(expression)
+	if err, isError := value.(*eval.Error); isError {
+		p.addErrorMessage(fmt.Sprintf("couldn't evaluate array size (%d:%d):\n%s", err.Token.Line, err.Token.Position, err.Message))
+		return 0
+	}
+
+	integer, isInteger := value.(*eval.Integer)
+	if !isInteger || integer.Value < 0 || integer.Value > math.MaxInt32 {
+		p.addErrorMessage("array size must be a positive integer, got " + source(expression))
+		return 0
+	}
+
+	return integer.Value
+}
+
 func (p *Parser) parsePrefix() ast.Expression {
 	fn, ok := p.prefixFunc[p.currentToken.Type]
 	if !ok {
 		p.addErrorMessage("unknown token to parse on prefix")
-		p.nextToken()
-		return &ast.Integer{Value: 1}
+		bad := &ast.BadExpression{Node: &node.Node{Type: ctypes.TODO(), Token: p.currentToken}}
+		// leave the tokens that delimit statements and blocks to recover on them
+		if p.currentToken.Type != token.SEMICOLON && p.currentToken.Type != token.LBRACE && p.currentToken.Type != token.RBRACE {
+			p.nextToken()
+		}
+
+		return bad
 	}
 
 	p.checkPrefixErrors()
+	start := p.currentToken.Span()
 	p.previousExpression = fn()
+	p.setSpan(p.previousExpression, start)
 	return p.previousExpression
 }
 
@@ -745,12 +945,24 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	op := p.currentTokenToOperation()
 	p.checkPrefixErrors()
 	tok := p.nextToken()
+	switch p.currentToken.Type {
+	case token.INT, token.HEX, token.BINARY, token.OCTAL:
+		p.negative = op == ops.Subtract
+	}
+
+	right := p.parseExpression(p.precedencePrefix())
+	folded := p.folded
+	p.folded = false
+	if integer, isInteger := right.(*ast.Integer); isInteger && folded {
+		return integer
+	}
+
 	left := &ast.PrefixOperation{
 		Node: &node.Node{
 			Token: tok,
 			Type:  ctypes.TODO(),
 		},
-		Right:     p.parseExpression(p.precedencePrefix()),
+		Right:     right,
 		Operation: op,
 	}
 
@@ -764,7 +976,9 @@ func (p *Parser) parseExpression(prec int) ast.Expression {
 		if infix == nil {
 			return prefixExpr
 		}
+		start := prefixExpr.Span()
 		prefixExpr = infix(prefixExpr)
+		p.setSpan(prefixExpr, start)
 	}
 
 	p.previousExpression = prefixExpr
@@ -789,7 +1003,7 @@ func (p *Parser) parseInfix(expression ast.Expression) ast.Expression {
 func (p *Parser) parseCall(expression ast.Expression) ast.Expression {
 	paren := p.nextToken()
 	var expressions []ast.Expression
-	for p.currentToken.Type != token.RPAREN && p.currentToken.Type != token.EOF {
+	for p.currentToken.Type != token.RPAREN && p.currentToken.Type != token.EOF && !p.panicking {
 		if len(expressions) > 0 {
 			p.expect(token.COMMA)
 			p.nextToken()
@@ -824,6 +1038,8 @@ func (p *Parser) parseIndex(expression ast.Expression) ast.Expression {
 }
 
 func (p *Parser) parseInteger() ast.Expression {
+	negative := p.negative
+	p.negative = false
 	t := p.nextToken()
 	base := 10
 	literal := t.Literal
@@ -834,6 +1050,18 @@ func (p *Parser) parseInteger() ast.Expression {
 	} else if t.Type == token.HEX {
 		base = 16
 		literal = literal[2:]
+	} else if t.Type == token.OCTAL {
+		base = 8
+		literal = literal[2:]
+	}
+
+	if !p.checkDigits(t, literal, base) {
+		return &ast.Integer{Node: &node.Node{Type: ctypes.I32, Token: t}}
+	}
+
+	literal, suffix := splitNumberSuffix(literal, base)
+	if suffix != "" {
+		return p.parseIntegerWithSuffix(t, literal, base, suffix, negative)
 	}
 
 	integer, err := strconv.ParseInt(literal, base, 64)
@@ -842,13 +1070,13 @@ func (p *Parser) parseInteger() ast.Expression {
 		if numErr, isNumErr := err.(*strconv.NumError); isNumErr && numErr.Err == strconv.ErrRange {
 			uinteger, err := strconv.ParseUint(literal, base, 64)
 			if err != nil {
-				p.addErrorMessage(err.Error())
+				p.addErrorAt(t, err.Error())
 			} else {
 				integer = int64(uinteger)
 				ty = ctypes.U64
 			}
 		} else {
-			p.addErrorMessage(err.Error())
+			p.addErrorAt(t, err.Error())
 		}
 	} else if integer > 2147483647 {
 		ty = ctypes.I64
@@ -863,15 +1091,126 @@ func (p *Parser) parseInteger() ast.Expression {
 	}
 }
 
+// parseIntegerWithSuffix parses an integer like 10u8 whose type is the suffix,
+// decimal integers can also have a float suffix like 10f32. Negative literals
+// of signed types can be one more than the positive ones, like -128i8.
+func (p *Parser) parseIntegerWithSuffix(t token.Token, literal string, base int, suffix string, negative bool) ast.Expression {
+	ty := ctypes.LiteralToType(suffix)
+	if _, isFloat := ty.(*ctypes.Float); isFloat && t.Type == token.INT {
+		return p.parseFloatWithType(t, literal, ty)
+	}
+
+	var bits uint
+	signed := false
+	switch integerType := ty.(type) {
+	case *ctypes.Integer:
+		bits = integerType.BitSize
+		signed = true
+	case *ctypes.UInteger:
+		bits = integerType.BitSize
+	}
+
+	if bits == 0 {
+		p.addErrorAt(t, fmt.Sprintf("invalid suffix %s for integer literal %s", suffix, t.Literal))
+		return &ast.Integer{Node: &node.Node{Type: ctypes.I32, Token: t}}
+	}
+
+	integer, err := strconv.ParseUint(literal, base, int(bits))
+	if numErr, isNumErr := err.(*strconv.NumError); isNumErr && numErr.Err == strconv.ErrRange {
+		p.addErrorAt(t, fmt.Sprintf("integer literal %s overflows %s", t.Literal, ty.String()))
+	} else if err != nil {
+		p.addErrorAt(t, err.Error())
+	} else if limit := uint64(1) << (bits - 1); signed && (integer > limit || integer == limit && !negative) {
+		sign := ""
+		if negative {
+			sign = "-"
+		}
+
+		p.addErrorAt(t, fmt.Sprintf("integer literal %s%s overflows %s", sign, t.Literal, ty.String()))
+	} else if signed && integer == limit {
+		// the smallest value doesn't fit without its sign, so the negation is folded into the literal
+		integer = -limit
+		p.folded = true
+	}
+
+	return &ast.Integer{
+		Node: &node.Node{
+			Type:  ty,
+			Token: t,
+		},
+		Value: int64(integer),
+	}
+}
+
+var baseNames = map[int]string{2: "binary", 8: "octal", 10: "decimal", 16: "hexadecimal"}
+
+// checkDigits reports the digits of an integer literal that aren't valid in its base, like the 9 of 0o19,
+// and literals without digits like 0x
+func (p *Parser) checkDigits(t token.Token, digits string, base int) bool {
+	for i := 0; i < len(digits); i++ {
+		ch := digits[i]
+		if isDigitOfBase(ch, base) || ch == '_' {
+			continue
+		}
+
+		if '0' <= ch && ch <= '9' {
+			p.addErrorAt(t, fmt.Sprintf("invalid digit '%c' in %s literal", ch, baseNames[base]))
+			return false
+		}
+
+		// the rest is the suffix
+		digits = digits[:i]
+		break
+	}
+
+	if digits == "" {
+		p.addErrorAt(t, fmt.Sprintf("%s literal %s has no digits", baseNames[base], t.Literal))
+		return false
+	}
+
+	return true
+}
+
+// splitNumberSuffix removes the digit separators of a number literal and
+// splits the type suffix from the digits, like 1_000u32 into 1000 and u32
+func splitNumberSuffix(literal string, base int) (string, string) {
+	i := 0
+	for i < len(literal) && (isDigitOfBase(literal[i], base) || literal[i] == '.' ||
+		(literal[i] == '_' && i+1 < len(literal) && isDigitOfBase(literal[i+1], base))) {
+		i++
+	}
+
+	return strings.ReplaceAll(literal[:i], "_", ""), literal[i:]
+}
+
+func isDigitOfBase(ch byte, base int) bool {
+	digit, err := strconv.ParseUint(string(ch), base, 8)
+	return err == nil && digit < uint64(base)
+}
+
 func (p *Parser) parseFloat() ast.Expression {
 	t := p.nextToken()
-	float, err := strconv.ParseFloat(t.Literal, 64)
+	literal, suffix := splitNumberSuffix(t.Literal, 10)
+	var ty ctypes.Type = ctypes.F32
+	if suffix != "" {
+		ty = ctypes.LiteralToType(suffix)
+		if _, isFloat := ty.(*ctypes.Float); !isFloat {
+			p.addErrorAt(t, fmt.Sprintf("invalid suffix %s for float literal %s", suffix, t.Literal))
+			ty = ctypes.F32
+		}
+	}
+
+	return p.parseFloatWithType(t, literal, ty)
+}
+
+func (p *Parser) parseFloatWithType(t token.Token, literal string, ty ctypes.Type) ast.Expression {
+	float, err := strconv.ParseFloat(literal, 64)
 	if err != nil {
-		p.addErrorMessage("couldn't parse float " + t.Literal + ", because of given error: " + err.Error())
+		p.addErrorAt(t, "couldn't parse float " + t.Literal + ", because of given error: " + err.Error())
 	}
 	return &ast.Float{
 		Node: &node.Node{
-			Type:  ctypes.F32,
+			Type:  ty,
 			Token: t,
 		},
 		Value: float,
@@ -928,6 +1267,12 @@ func (p *Parser) parseAt() ast.Expression {
 }
 
 func (p *Parser) parseBlock() *ast.Block {
+	p.enterConstantScope()
+	defer p.leaveConstantScope()
+	return p.parseBlockStatements()
+}
+
+func (p *Parser) parseBlockStatements() *ast.Block {
 	currentToken := p.currentToken
 	if p.currentToken.Type == token.LBRACE {
 		p.nextToken()
@@ -935,16 +1280,32 @@ func (p *Parser) parseBlock() *ast.Block {
 			Statements: []ast.Statement{},
 			Token:      currentToken,
 		}
-		for p.currentToken.Type != token.RBRACE && p.currentToken.Type != token.EOF {
+		// the statements of the block recover on their own, but if the statement that has the block
+		// had an error before it, it's still a bad statement
+		panicking := p.panicking
+		p.panicking = false
+		p.depth++
+		for p.currentToken.Type != token.RBRACE && p.currentToken.Type != token.EOF && !p.unwinding {
 			block.Statements = append(block.Statements, p.parseStatement())
 		}
+		p.depth--
+		p.panicking = panicking
+		if p.unwinding {
+			// the missing brace has been reported already
+			p.setSpan(block, currentToken.Span())
+			return block
+		}
+
 		p.expect(token.RBRACE)
 		p.nextToken()
+		p.setSpan(block, currentToken.Span())
 		return block
 	}
 
 	stmt := p.parseStatement()
-	return &ast.Block{Statements: []ast.Statement{stmt}, Token: currentToken}
+	block := &ast.Block{Statements: []ast.Statement{stmt}, Token: currentToken}
+	block.SetSpan(stmt.Span())
+	return block
 }
 
 func (p *Parser) parseIf() ast.Statement {
@@ -960,13 +1321,15 @@ func (p *Parser) parseIf() ast.Statement {
 			elseBlock = p.parseBlock()
 			break
 		} else if p.currentToken.Type == token.IF {
-			p.nextToken()
+			start := p.nextToken()
 			expression := p.parseExpression(0)
 			block := p.parseBlock()
-			elseWithConditions = append(elseWithConditions, &ast.ConditionPlusBlock{
+			elseIf := &ast.ConditionPlusBlock{
 				Block:     block,
 				Condition: expression,
-			})
+			}
+			p.setSpan(elseIf, start.Span())
+			elseWithConditions = append(elseWithConditions, elseIf)
 		}
 	}
 
@@ -1006,22 +1369,67 @@ func (p *Parser) parseBuiltinCallTypes(builtinRequirements BuiltinFunctionParseR
 	return types
 }
 
-// #if <constant_condition> <block>
+// #if <constant_condition> <block> [#elif <constant_condition> <block>]... [#else <block>]
+// Every block is parsed, but only the block of the first condition that is true is kept and
+// the conditions, constants and array sizes of the other blocks aren't evaluated.
 func (p *Parser) parseMacroIf() ast.Statement {
-	p.nextToken()
-	expressionIf := p.parseExpression(0)
-	value := eval.EvaluateConstantExpression(expressionIf)
-	if err, isError := value.(*eval.Error); isError {
-		p.addErrorMessage(fmt.Sprintf("error evaluating macro #if (%d:%d):\n%s", err.Token.Line, err.Token.Position, err.Message))
-		return &ast.MacroBlock{Block: &ast.Block{Statements: []ast.Statement{}}}
+	macroBlock := &ast.MacroBlock{Block: &ast.Block{Statements: []ast.Statement{}}}
+	chosen := false
+	for {
+		p.nextToken()
+		expressionIf := p.parseExpression(0)
+		// conditions after the chosen block aren't evaluated, they may use symbols that aren't defined
+		var value eval.Value = &eval.Integer{}
+		if !chosen && p.canEvaluate(expressionIf) {
+			value = p.evaluate(expressionIf)
+		}
+
+		if err, isError := value.(*eval.Error); isError {
+			p.addErrorMessage(fmt.Sprintf("error evaluating macro #if (%d:%d):\n%s", err.Token.Line, err.Token.Position, err.Message))
+			return macroBlock
+		}
+
+		isChosen := !chosen && value.IsTruthy()
+		block := p.parseMacroBranch(isChosen)
+		if isChosen {
+			macroBlock.Block = block
+			chosen = true
+		}
+
+		if p.currentToken.Type != token.MACRO_ELIF {
+			break
+		}
 	}
 
-	block := p.parseBlock()
-	if value.IsTruthy() {
-		return &ast.MacroBlock{Block: block}
+	if p.currentToken.Type == token.MACRO_ELSE {
+		p.nextToken()
+		block := p.parseMacroBranch(!chosen)
+		if !chosen {
+			macroBlock.Block = block
+		}
+	}
+
+	return macroBlock
+}
+
+// parseMacroBranch parses the block of an #if branch, the statements of the chosen branch
+// belong to the scope of the #if, so its constants are kept.
+func (p *Parser) parseMacroBranch(isChosen bool) *ast.Block {
+	if !isChosen {
+		p.inactive++
+		defer func() { p.inactive-- }()
+	}
+
+	p.enterConstantScope()
+	block := p.parseBlockStatements()
+	constants := p.leaveConstantScope()
+	if isChosen {
+		for name, value := range constants {
+			p.constants[len(p.constants)-1][name] = value
+		}
 	}
 
-	return &ast.MacroBlock{Block: &ast.Block{Statements: []ast.Statement{}}}
+	return block
 }
 
 func (p *Parser) parseBuiltinCallParameters(builtinRequirements BuiltinFunctionParseRequirements) []ast.Expression {
@@ -1152,16 +1560,19 @@ func (p *Parser) parseExtern() ast.Statement {
 		return &ast.ExternStatement{}
 	}
 
+	statement := &ast.ExternStatement{
+		Token: extern,
+		Type:  t,
+	}
+
 	if fun, ok := t.(*ctypes.Function); !ok || fun.Name == "" {
 		p.addErrorMessage("badly formed external function")
 	} else {
 		fun.ExternalName = ast.RetrieveID(fun.Name)
+		p.functions[fun.ExternalName] = statement
 	}
 
-	return &ast.ExternStatement{
-		Token: extern,
-		Type:  t,
-	}
+	return statement
 }
 
 func (p *Parser) afterFixDoubleError(prev ast.Expression) ast.Expression {
@@ -1198,11 +1609,13 @@ func (p *Parser) parseCaseStatement() *ast.CaseStatement {
 	caseKeyword := p.nextToken()
 	condition := p.parseExpression(0)
 	block := p.parseBlock()
-	return &ast.CaseStatement{
+	caseStatement := &ast.CaseStatement{
 		Case:  condition,
 		Block: block,
 		Token: caseKeyword,
 	}
+	p.setSpan(caseStatement, caseKeyword.Span())
+	return caseStatement
 }
 
 //
@@ -1242,7 +1655,7 @@ func (p *Parser) parseCharLiteral() ast.Expression {
 			Token: c,
 			Type:  ctypes.I8,
 		},
-		Value: int64(c.Literal[0]),
+		Value: int64(int8(c.Literal[0])),
 	}
 }
 
@@ -45,6 +45,18 @@ func (p *Parser) parsePossibleAssignment() ast.Statement {
 	}
 
 	if commaExpr, ok := expr.(*ast.CommaExpressions); ok && p.currentToken.Type == token.COLON {
+		names := make([]string, 0, len(commaExpr.Expressions))
+		for _, expression := range commaExpr.Expressions {
+			identifier, isIdentifier := expression.(*ast.Identifier)
+			if !isIdentifier {
+				// the rest of the declaration is still parsed to recover after it
+				p.addErrorAt(expression.GetToken(), "expected a variable name to declare, got "+source(expression))
+				continue
+			}
+
+			names = append(names, identifier.Name)
+		}
+
 		declToken := p.nextToken()
 		var t ctypes.Type = ctypes.TODO()
 		if token.ASSIGN != p.currentToken.Type {
@@ -55,7 +67,7 @@ func (p *Parser) parsePossibleAssignment() ast.Statement {
 		p.nextToken()
 		return &ast.MultipleDeclarationStatement{
 			Token:      declToken,
-			Names:      ast.IdentifiersToStrings(commaExpr.Expressions),
+			Names:      names,
 			Type:       t,
 			Expression: p.parseExpression(0),
 			Constant:   false,
@@ -5,20 +5,18 @@ type BuiltinFunctionParseRequirements struct {
 	Parameters int
 }
 
-var builtinFunctions = map[string]BuiltinFunctionParseRequirements{}
-
 const UndefinedNumberOfParameters = -1
 
 // AddBuiltinFunction adds a requirement of parsing a builtin function
 func (p *Parser) addBuiltinFunction(name string, numberOfTypes, numberOfParameters int) {
-	builtinFunctions[name] = BuiltinFunctionParseRequirements{
+	p.builtinFunctions[name] = BuiltinFunctionParseRequirements{
 		Types:      numberOfTypes,
 		Parameters: numberOfParameters,
 	}
 }
 
 func (p *Parser) getBuiltinFunctionRequirements(name string) BuiltinFunctionParseRequirements {
-	return builtinFunctions[name]
+	return p.builtinFunctions[name]
 }
 
 func (p *Parser) initBuiltinFunctions() {
@@ -31,4 +29,14 @@ func (p *Parser) initBuiltinFunctions() {
 	p.addBuiltinFunction("unreachable", 0, 0)
 	p.addBuiltinFunction("asm", 1, UndefinedNumberOfParameters)
 	p.addBuiltinFunction("add_compiler_flag", 0, UndefinedNumberOfParameters)
+	p.addBuiltinFunction("atomic_load", 0, 2)
+	p.addBuiltinFunction("atomic_store", 0, 3)
+	p.addBuiltinFunction("atomic_add", 0, 3)
+	p.addBuiltinFunction("atomic_cas", 0, 4)
+	p.addBuiltinFunction("fence", 0, 1)
+	p.addBuiltinFunction("splat", 1, 1)
+	p.addBuiltinFunction("extract", 0, 2)
+	p.addBuiltinFunction("insert", 0, 3)
+	p.addBuiltinFunction("shuffle", 0, UndefinedNumberOfParameters)
+	p.addBuiltinFunction("reduce", 0, 2)
 }
@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"errors"
+
+	"github.com/gabivlj/candice/internals/ast"
+	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/eval"
+)
+
+// constantEnvironment lets #if and array sizes use the constants that are declared before them,
+// the defines and the platform symbols.
+type constantEnvironment struct {
+	p *Parser
+
+	// global is true for the bodies of comptime functions, they can only see global constants
+	global bool
+}
+
+func (c constantEnvironment) Constant(identifier *ast.Identifier) (eval.Value, bool) {
+	name := identifier.Token.Literal
+	innermost := len(c.p.constants) - 1
+	if c.global {
+		innermost = 0
+	}
+
+	for i := innermost; i >= 0; i-- {
+		if value, ok := c.p.constants[i][name]; ok {
+			return value, true
+		}
+	}
+
+	return eval.Symbol(name)
+}
+
+func (c constantEnvironment) Type(t ctypes.Type) (ctypes.Type, bool) {
+	// aliases and structs aren't known until the semantic analysis
+	return nil, false
+}
+
+// Function returns the comptime functions that are declared before the expression
+func (c constantEnvironment) Function(callee ast.Expression) (*ast.FunctionDeclarationStatement, eval.Environment, error) {
+	identifier, isIdentifier := callee.(*ast.Identifier)
+	if !isIdentifier {
+		return nil, nil, errors.New("can't call " + callee.String() + " here, only comptime functions of the same module can be called")
+	}
+
+	function, err := eval.ComptimeFunction(identifier.Token.Literal, c.p.functions[identifier.Token.Literal])
+	return function, constantEnvironment{p: c.p, global: true}, err
+}
+
+func (p *Parser) evaluate(expression ast.Expression) eval.Value {
+	return eval.Evaluate(expression, constantEnvironment{p: p})
+}
+
+// canEvaluate returns false when the expression might be incomplete because of a syntax error,
+// evaluating it would only report errors about the parts that are missing, and inside the
+// #if branches that aren't chosen
+func (p *Parser) canEvaluate(expression ast.Expression) bool {
+	if p.panicking || p.inactive > 0 || expression == nil {
+		return false
+	}
+
+	complete := true
+	ast.Inspect(expression, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.BadExpression, *ast.BadStatement:
+			complete = false
+		case *ast.ExpressionBlock:
+			complete = complete && n.Block != nil
+		}
+
+		return complete
+	})
+
+	return complete
+}
+
+// source returns the source of the node for error messages, or its token if the source isn't known
+func source(node ast.Node) string {
+	if source := node.Span().Source(); source != "" {
+		return source
+	}
+
+	return node.GetToken().Literal
+}
+
+func (p *Parser) enterConstantScope() {
+	p.constants = append(p.constants, map[string]eval.Value{})
+}
+
+func (p *Parser) leaveConstantScope() map[string]eval.Value {
+	scope := p.constants[len(p.constants)-1]
+	p.constants = p.constants[:len(p.constants)-1]
+	return scope
+}
+
+// declareConstant stores the value of a constant declaration, if it can't be evaluated
+// the error is stored so it isn't confused with a constant of an outer scope.
+func (p *Parser) declareConstant(declaration *ast.DeclarationStatement) {
+	var value eval.Value = &eval.Error{Message: "constant " + declaration.Token.Literal + " has a syntax error", Token: declaration.Token}
+	if p.canEvaluate(declaration.Expression) {
+		value = p.evaluate(declaration.Expression)
+	}
+
+	p.constants[len(p.constants)-1][declaration.Token.Literal] = value
+}
@@ -1 +1,67 @@
 package parser
+
+import (
+	"fmt"
+
+	"github.com/gabivlj/candice/internals/token"
+	"github.com/gabivlj/candice/pkg/logger"
+)
+
+// SyntaxError is an error found while parsing a file
+type SyntaxError struct {
+	Span token.Span
+	// Message describes the error without the source that caused it
+	Message string
+	// text is the message with the source, as it's printed on the terminal
+	text string
+}
+
+func (e *SyntaxError) Error() string {
+	return e.text
+}
+
+// Diagnostic returns the error with its location so tools can read it
+func (e *SyntaxError) Diagnostic() logger.Diagnostic {
+	return logger.Diagnostic{
+		Severity:  logger.SeverityError,
+		Code:      "syntax",
+		Message:   e.Message,
+		File:      e.Span.File.Name(),
+		Line:      e.Span.Line,
+		Column:    e.Span.Position,
+		EndLine:   e.Span.EndLine,
+		EndColumn: e.Span.EndPosition,
+	}
+}
+
+func (p *Parser) addErrorMessage(message string) {
+	p.addErrorWithDetails(message, "")
+}
+
+// addErrorWithDetails adds an error on the current token, details are only printed on the terminal
+func (p *Parser) addErrorWithDetails(message, details string) {
+	p.addErrorOn(p.currentToken, message, details)
+}
+
+// addErrorAt adds an error on a token that was already parsed, like a number literal
+func (p *Parser) addErrorAt(t token.Token, message string) {
+	p.addErrorOn(t, message, "")
+}
+
+func (p *Parser) addErrorOn(t token.Token, message, details string) {
+	if p.panicking {
+		return
+	}
+
+	p.panicking = true
+	text := message
+	if details != "" {
+		text += "\n" + details
+	}
+
+	p.Errors = append(p.Errors, &SyntaxError{
+		Span:    t.Span(),
+		Message: message,
+		text:    fmt.Sprintf("on %s 'token: %s': %s\n\n%s happened here", t.Span(), t.Literal, text, p.lexer.Underline(t.Span())),
+	})
+}
@@ -3,9 +3,14 @@ package parser
 import (
 	"fmt"
 	"log"
+	"os"
+	"path"
+	"strings"
 	"testing"
 
+	"github.com/gabivlj/candice/internals/ast"
 	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/eval"
 	"github.com/gabivlj/candice/internals/lexer"
 	"github.com/gabivlj/candice/pkg/a"
 	"github.com/gabivlj/candice/pkg/logger"
@@ -60,6 +65,24 @@ func TestParser_ParseTypeI8(t *testing.T) {
 	a.Assert(tt.(*ctypes.Integer).BitSize == 8)
 }
 
+func TestParser_ParseTypeVector(t *testing.T) {
+	src := "*[4]vec8 f32"
+	lex := lexer.New(src)
+	p := New(lex)
+	tt := p.parseType()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+	a.AssertEqual(src, tt.String())
+	vector := tt.(*ctypes.Pointer).Inner.(*ctypes.Array).Inner.(*ctypes.Vector)
+	a.Assert(vector.Length == 8 && vector.Inner == ctypes.F32)
+
+	p = New(lexer.New("vec3 f32"))
+	p.parseType()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+	p = New(lexer.New("vec4 *i8"))
+	p.parseType()
+	a.Assert(len(p.Errors) == 1)
+}
+
 func TestParser_ParseBinaryOperation(t *testing.T) {
 	src := "3+3"
 	lex := lexer.New(src)
@@ -355,6 +378,349 @@ default {
 	}
 }
 
+func TestParser_ArraySize(t *testing.T) {
+	p := New(lexer.New(`const SIZE := 4; #if SIZE > 2 { const DOUBLE := SIZE * 2; } func main() { const LOCAL := DOUBLE + 1; a : [LOCAL]i32 = [SIZE * DOUBLE]i32{}; }`))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+	a.AssertEqual(program.String(), `SIZE : = 4;
+DOUBLE : = (SIZE * 2);
+func main()  {
+LOCAL : = (DOUBLE + 1);
+a :[9]i32 = [32]i32 {};
+}
+`)
+
+	for _, program := range []string{
+		`a := [SIZE]i32{}`,
+		`func main() { const SIZE := 3; } a := [SIZE]i32{}`,
+		`a := [0 - 1]i32{}`,
+		`a := [1.5]i32{}`,
+	} {
+		p := New(lexer.New(program))
+		p.Parse()
+		a.Assert(len(p.Errors) != 0, program)
+	}
+}
+
+func TestParser_Comptime(t *testing.T) {
+	p := New(lexer.New(`pub comptime func double(n i32) i32 { return n * 2; } #if double(2) == 4 { a := [double(3)]i32{}; }`))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+	a.AssertEqual(program.String(), `comptime func double(n i32) i32 {
+return (n * 2);
+}
+a : = [6]i32 {};
+`)
+
+	for _, program := range []string{
+		`func double(n i32) i32 { return n * 2; } a := [double(3)]i32{}`,
+		`extern func rand() i32; a := [rand()]i32{}`,
+		`a := [double(3)]i32{}; comptime func double(n i32) i32 { return n * 2; }`,
+	} {
+		p := New(lexer.New(program))
+		p.Parse()
+		a.Assert(len(p.Errors) != 0, program)
+	}
+}
+
+func TestParser_NumberLiterals(t *testing.T) {
+	tests := []struct {
+		literal string
+		value   string
+		ty      ctypes.Type
+	}{
+		{"1_000_000", "1000000", ctypes.I32},
+		{"0o17", "15", ctypes.I32},
+		{"10u8", "10", ctypes.U8},
+		{"0xFFu8", "255", ctypes.U8},
+		{"0b1000_0000i16", "128", ctypes.I16},
+		{"18446744073709551615u64", "-1", ctypes.U64},
+		{"1.5f64", "1.5", ctypes.F64},
+		{"3f32", "3", ctypes.F32},
+		{"'\\x41'", "'A'", ctypes.I8},
+	}
+
+	for _, test := range tests {
+		p := New(lexer.New(test.literal))
+		expression := p.parseExpression(0)
+		a.Assert(len(p.Errors) == 0, test.literal, p.Errors)
+		a.AssertEqual(expression.String(), test.value)
+		a.AssertEqual(expression.GetType().String(), test.ty.String())
+	}
+
+	for _, literal := range []string{"300u8", "128i8", "1.5u8", "10x", "1bool", "1_", "-129i8", "32768i16", "-32769i16",
+		"-0x81i8", "9223372036854775808i64", "0o9", "0b12", "0x", "0ou8"} {
+		p := New(lexer.New(literal))
+		p.parseExpression(0)
+		a.Assert(len(p.Errors) == 1, literal, p.Errors)
+	}
+
+	// the smallest values of signed types only fit when they are negated
+	for _, literal := range []string{"-128i8", "-32768i16", "-0x80i8", "-2147483648i32", "-9223372036854775808i64", "127i8", "-(127i8)"} {
+		p := New(lexer.New(literal))
+		p.parseExpression(0)
+		a.Assert(len(p.Errors) == 0, literal, p.Errors)
+	}
+
+	p := New(lexer.New("-128i8"))
+	integer := p.parseExpression(0).(*ast.Integer)
+	a.Assert(integer.Value == -128 && integer.Type == ctypes.I8, integer.Value, integer.Type)
+
+	// the negation is part of the literal, so it's only written once
+	p = New(lexer.New("x := -128i8 + -127i8 - -0x80i8;"))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+	a.AssertEqual(program.String(), "x : = ((-128 + -127) - -128);\n")
+
+	p = New(lexer.NewFile("octal.cd", "x := 0o19;"))
+	p.Parse()
+	a.Assert(len(p.Errors) == 1, p.Errors)
+	err := p.Errors[0].(*SyntaxError)
+	a.AssertEqual(err.Message, "invalid digit '9' in octal literal")
+	a.Assert(err.Span.Position == 6 && err.Span.EndPosition == 10, err.Span)
+
+	// bad suffixes and separators are reported on the literal, not on what comes after it
+	for _, literal := range []string{"10u7", "1.5f16", "1__0", "1_", "99999999999999999999"} {
+		p := New(lexer.NewFile("suffix.cd", "x := "+literal+";"))
+		p.Parse()
+		a.Assert(len(p.Errors) == 1, literal, p.Errors)
+		span := p.Errors[0].(*SyntaxError).Span
+		a.Assert(span.Position == 6 && span.EndPosition == uint32(6+len(literal)), literal, span)
+	}
+}
+
+func TestParser_Recovery(t *testing.T) {
+	p := New(lexer.New(`
+func a() {
+    x := 3 +;
+    y := 4;
+    z := (1 + ;
+    w := 5;
+}
+
+func b() i32 {
+    return ) ;
+}
+
+func c() {
+    if x == {
+        q := 1;
+    }
+    r := ;
+    x := 1 +
+
+func d() {}`))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 6, p.Errors)
+	a.Assert(len(program.Statements) == 4, program)
+
+	body := program.Statements[0].(*ast.FunctionDeclarationStatement).Block.Statements
+	a.Assert(len(body) == 4, body)
+	_, isBad := body[0].(*ast.BadStatement)
+	a.Assert(isBad, body[0])
+	a.AssertEqual(body[1].String(), "y : = 4;")
+	a.AssertEqual(body[3].String(), "w : = 5;")
+
+	body = program.Statements[2].(*ast.FunctionDeclarationStatement).Block.Statements
+	a.Assert(len(body) == 3, body)
+	a.AssertEqual(program.Statements[3].(*ast.FunctionDeclarationStatement).FunctionType.Name, ast.CreateIdentifier("d", p.ID))
+}
+
+func TestParser_RecoveryMultipleDeclaration(t *testing.T) {
+	p := New(lexer.NewFile("declaration.cd", `
+func main() {
+    a, 1 := 3, 4;
+    b, c.d, e := 5, 6, 7;
+    f, g := 8, 9;
+}`))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 2, p.Errors)
+	a.AssertEqual(p.Errors[0].(*SyntaxError).Message, "expected a variable name to declare, got 1")
+	a.AssertEqual(p.Errors[1].(*SyntaxError).Message, "expected a variable name to declare, got c.d")
+
+	body := program.Statements[0].(*ast.FunctionDeclarationStatement).Block.Statements
+	a.Assert(len(body) == 3, body)
+	declaration, isDeclaration := body[2].(*ast.MultipleDeclarationStatement)
+	a.Assert(isDeclaration, body[2])
+	a.Assert(len(declaration.Names) == 2, declaration.Names)
+}
+
+func TestParser_MissingTypes(t *testing.T) {
+	p := New(lexer.NewFile("types.cd", `
+struct A {
+    x i32
+    y
+}
+
+union U { a i32 b }
+
+func f(x i32, y) {}
+
+func main() { g := func(z) {}; }`))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 4, p.Errors)
+	for i, message := range []string{"field y doesn't have a type", "field b doesn't have a type",
+		"parameter y doesn't have a type", "parameter z doesn't have a type"} {
+		a.AssertEqual(p.Errors[i].(*SyntaxError).Message, message)
+	}
+
+	// the error is on the name, not on what comes after it
+	a.Assert(p.Errors[0].(*SyntaxError).Span.Line == 4, p.Errors[0])
+	a.Assert(len(program.Statements) == 4, program.Statements)
+}
+
+func TestParser_RecoveryConstants(t *testing.T) {
+	sources := []string{
+		"const D 0o9 := 2;",
+		"const D i32 = 2;\nconst E := D + ;",
+		"func main() {\n    var x [3 + ]i32;\n}",
+		"#if 1 + {\n    const A := 1;\n}",
+	}
+
+	for _, source := range sources {
+		p := New(lexer.NewFile("recovery.cd", source))
+		p.Parse()
+		a.Assert(len(p.Errors) > 0, source)
+	}
+}
+
+func TestParser_Spans(t *testing.T) {
+	source := "func main() {\n    x := foo(1, 2) + bar[3] as i64;\n    if x > 2 {\n        @print(x);\n    }\n}\n"
+	p := New(lexer.NewFile("spans.cd", source))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+
+	function := program.Statements[0].(*ast.FunctionDeclarationStatement)
+	a.AssertEqual(fmt.Sprint(function.Span()), "spans.cd:1:1")
+	a.Assert(function.Span().EndLine == 6 && function.Span().EndPosition == 2, function.Span())
+
+	declaration := function.Block.Statements[0].(*ast.DeclarationStatement)
+	a.AssertEqual(declaration.Span().Underline(source), "    x := foo(1, 2) + bar[3] as i64;\n    ^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^")
+	sum := declaration.Expression.(*ast.BinaryOperation)
+	a.AssertEqual(sum.Span().Underline(source), "    x := foo(1, 2) + bar[3] as i64;\n         ^^^^^^^^^^^^^^^^^^^^^^^^^")
+	a.AssertEqual(sum.Left.Span().Underline(source), "    x := foo(1, 2) + bar[3] as i64;\n         ^^^^^^^^^")
+	a.AssertEqual(sum.Right.Span().Underline(source), "    x := foo(1, 2) + bar[3] as i64;\n                     ^^^^^^^^^^^^^")
+
+	ifStatement := function.Block.Statements[1]
+	a.Assert(ifStatement.Span().Line == 3 && ifStatement.Span().EndLine == 5, ifStatement.Span())
+
+	p = New(lexer.NewFile("spans.cd", "func main() {\n    x := 3 +;\n}"))
+	p.Parse()
+	a.Assert(len(p.Errors) == 1, p.Errors)
+	a.Assert(strings.Contains(p.Errors[0].Error(), "on spans.cd:2:13"), p.Errors)
+}
+
+func TestParser_Walk(t *testing.T) {
+	files, err := os.ReadDir("../tests/src")
+	a.Assert(err == nil, err)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		source, err := os.ReadFile(path.Join("../tests/src", file.Name()))
+		a.Assert(err == nil, err)
+		p := New(lexer.New(string(source)))
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			continue
+		}
+
+		nodes := 0
+		ast.Inspect(program, func(node ast.Node) bool {
+			if node != nil {
+				nodes++
+			}
+
+			return true
+		})
+
+		rewritten := 0
+		printed := program.String()
+		ast.Rewrite(program, func(node ast.Node) ast.Node {
+			rewritten++
+			return node
+		})
+
+		a.Assert(nodes > 0 && nodes == rewritten, file.Name(), nodes, rewritten)
+		a.AssertEqual(program.String(), printed)
+	}
+}
+
+func TestParser_Doc(t *testing.T) {
+	p := New(lexer.New(`
+/// Point is a point.
+struct Point { x i32 }
+
+// not documented
+union Value { i i32 }
+
+/// add adds
+/// two numbers
+pub func add(a i32, b i32) i32 { /* /* nested */ */ return a + b; }`))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+	a.AssertEqual(program.Statements[0].(*ast.StructStatement).Doc, "Point is a point.")
+	a.AssertEqual(program.Statements[1].(*ast.UnionStatement).Doc, "")
+	a.AssertEqual(program.Statements[2].(*ast.FunctionDeclarationStatement).Doc, "add adds\ntwo numbers")
+	a.Assert(len(program.Comments) == 5, program.Comments)
+}
+
+func TestParser_MacroIf(t *testing.T) {
+	eval.SetDefines(map[string]string{"LEVEL": "2", "BACKEND": "gl", "DEBUG": ""})
+	defer eval.SetDefines(nil)
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{
+			expression: `#if LEVEL > 1 && DEBUG { a := 1; } b := 2;`,
+			expected:   "a : = 1;\nb : = 2;\n",
+		},
+		{
+			expression: `#if BACKEND == "vulkan" { a := 1; } #elif BACKEND == "gl" { a := 2; } #else { a := 3; }`,
+			expected:   "a : = 2;\n",
+		},
+		{
+			expression: `#if !DEBUG || LEVEL != 2 { a := 1; } #elif LEVEL < 2 { a := 2; } #else { a := 3; }`,
+			expected:   "a : = 3;\n",
+		},
+		{
+			// conditions after the chosen block aren't evaluated
+			expression: `#if LEVEL >= 2 { a := 1; } #elif UNDEFINED { a := 2; }`,
+			expected:   "a : = 1;\n",
+		},
+		{
+			expression: `#if 0 && UNDEFINED { a := 1; } #elif 1 || UNDEFINED { a := 2; }`,
+			expected:   "a : = 2;\n",
+		},
+		{
+			// branches that aren't chosen are only parsed
+			expression: `#if BACKEND == "vulkan" { #if UNDEFINED_VERSION > 7 { a := 1; } const SIZE := UNDEFINED_SIZE; var b [SIZE]i32; } #else { a := 2; }`,
+			expected:   "a : = 2;\n",
+		},
+		{
+			expression: `#if LEVEL == 1 { a := 1; } #elif LEVEL == 2 { #if !DEBUG { #if UNDEFINED { a := 2; } } #else { a := 3; } }`,
+			expected:   "a : = 3;\n",
+		},
+	}
+
+	for _, test := range tests {
+		evaluate(t, test.expression, test.expected)
+	}
+
+	for _, program := range []string{
+		`#if UNDEFINED { a := 1; }`,
+		`#if BACKEND > "gl" { a := 1; }`,
+		`#else { a := 1; }`,
+		`#elif 1 { a := 1; }`,
+	} {
+		p := New(lexer.New(program))
+		p.Parse()
+		a.Assert(len(p.Errors) != 0, program)
+	}
+}
+
 func evaluate(t *testing.T, expression, expected string) {
 	p := New(lexer.New(expression))
 	program := p.Parse()
@@ -365,3 +731,18 @@ func evaluate(t *testing.T, expression, expected string) {
 	output := program.String()
 	a.AssertEqual(output, expected)
 }
+
+func TestParser_SyntaxErrorDiagnostic(t *testing.T) {
+	p := New(lexer.NewFile("syntax.cd", "func main() {\n\tx := (3;\n}"))
+	p.Parse()
+	a.Assert(len(p.Errors) > 0)
+
+	err, ok := p.Errors[0].(*SyntaxError)
+	a.Assert(ok, p.Errors[0])
+	diagnostic := err.Diagnostic()
+	a.AssertEqual(diagnostic.Code, "syntax")
+	a.AssertEqual(diagnostic.Message, "expected a ), received a ';'")
+	a.AssertEqual(diagnostic.File, "syntax.cd")
+	a.Assert(diagnostic.Line == 2 && diagnostic.Column == 9 && diagnostic.EndColumn == 10, diagnostic)
+	a.Assert(strings.Contains(err.Error(), "happened here"), err.Error())
+}
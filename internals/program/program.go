@@ -39,7 +39,7 @@ func Init() {
 		logger.Error("", "Opening file", err.Error())
 	}
 
-	p := parser.New(lexer.New(string(code)))
+	p := parser.New(lexer.NewFile(file, string(code)))
 	tree := p.Parse()
 	if len(p.Errors) > 0 {
 		for _, err := range p.Errors {
@@ -3,7 +3,6 @@ package semantic
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"strconv"
@@ -37,6 +36,10 @@ type Semantic struct {
 	expectConstantExpression              bool
 	expectNonConstantExpression           bool
 
+	// functions has the function and extern declarations of the module by name, so constant
+	// expressions can call the comptime ones
+	functions map[string]ast.Statement
+
 	// We maintain references to other semantic analyzers here, those are generated by import statements.
 	// The structure of this map is
 	// ```go
@@ -57,9 +60,39 @@ type Semantic struct {
 
 	// path of the directory in the context where the compiler is running
 	ContextDirectoryPath string
+
+	// FilePath is the path of the file that this module was read from
+	FilePath string
+
+	// Dependencies maps the name of each dependency of the project to its directory,
+	// imports that start with the name of a dependency are resolved inside of it.
+	// Only the root module needs it, imported modules share the same dependencies.
+	Dependencies map[string]string
+
+	// SearchPaths are directories, like the project root, where imports are looked for
+	// when they can't be found relative to the importing file. Only the root module needs them.
+	SearchPaths []string
+
+	// StdDirectory is the directory of the standard library, imports that start with
+	// 'std/' are looked for there. Only the root module needs it.
+	StdDirectory string
+
+	// Checksum identifies the contents of this module and its imports, empty until ComputeChecksum is called
+	Checksum string
+
+	// loader is shared by every module of the same build
+	loader *loader
+	// key of this module on the loader
+	key            string
+	pendingImports []pendingImport
+	// holdsWorker is true when the module is being analyzed on one of the loader workers
+	holdsWorker bool
 }
 
-var paths map[string]*Semantic = map[string]*Semantic{}
+type pendingImport struct {
+	statement *ast.ImportStatement
+	future    *moduleFuture
+}
 
 func (s *Semantic) SizeOf() int64 {
 	return 0
@@ -71,9 +104,9 @@ func (s *Semantic) Alignment() int64 { return 0 }
 
 func (s *Semantic) String() string { return "MODULE" }
 
-func ResetPaths() {
-	paths = map[string]*Semantic{}
-}
+// ResetPaths is kept for compatibility, imported modules are cached per build
+// so there is nothing to reset anymore.
+func ResetPaths() {}
 
 func New() *Semantic {
 	s := &Semantic{
@@ -85,6 +118,7 @@ func New() *Semantic {
 		currentExpectedReturnType: ctypes.VoidType,
 		returns:                   false,
 		modules:                   map[string]*Semantic{},
+		functions:                 map[string]ast.Statement{},
 	}
 
 	s.builtinHandlers["cast"] = s.analyzeCast
@@ -96,6 +130,16 @@ func New() *Semantic {
 	s.builtinHandlers["unreachable"] = s.analyzeUnreachable
 	s.builtinHandlers["asm"] = s.analyzeAsm
 	s.builtinHandlers["add_compiler_flag"] = s.analyzeAddCompilerFlag
+	s.builtinHandlers["atomic_load"] = s.analyzeAtomicLoad
+	s.builtinHandlers["atomic_store"] = s.analyzeAtomicStore
+	s.builtinHandlers["atomic_add"] = s.analyzeAtomicAdd
+	s.builtinHandlers["atomic_cas"] = s.analyzeAtomicCas
+	s.builtinHandlers["fence"] = s.analyzeFence
+	s.builtinHandlers["splat"] = s.analyzeSplat
+	s.builtinHandlers["extract"] = s.analyzeExtract
+	s.builtinHandlers["insert"] = s.analyzeInsert
+	s.builtinHandlers["shuffle"] = s.analyzeShuffle
+	s.builtinHandlers["reduce"] = s.analyzeReduce
 
 	return s
 }
@@ -113,30 +157,51 @@ func (s *Semantic) leaveFrame() {
 }
 
 func (s *Semantic) error(msg string, tok token.Token) {
+	s.errorWithDetails(CodeSemantic, msg, "", tok)
+}
+
+// builtinError adds an error about the parameters of a builtin call
+func (s *Semantic) builtinError(msg string, tok token.Token) {
+	s.errorWithDetails(CodeBuiltin, msg, "", tok)
+}
+
+// errorWithDetails adds an error on tok, details are the source that caused it and they are
+// only printed on the terminal after the message
+func (s *Semantic) errorWithDetails(code ErrorCode, msg, details string, tok token.Token) {
+	s.errorOnSpan(code, msg, details, tok.Span())
+}
+
+// errorOnSpan is errorWithDetails for the errors that blame a whole expression instead of a token
+func (s *Semantic) errorOnSpan(code ErrorCode, msg, details string, span token.Span) {
+	message, hints := splitHints(msg)
+	s.addError(&SemanticError{Span: span, Code: code, Message: message, Hints: hints, text: msg + details})
+}
+
+func (s *Semantic) addError(err *SemanticError) {
 	if len(s.Errors) > 1 {
 		return
 	}
 
-	s.Errors = append(s.Errors, errors.New(fmt.Sprintf("[%d:%d] %s", tok.Line, tok.Position, msg)))
+	s.Errors = append(s.Errors, err)
 }
 
 func (s *Semantic) errorWithStatement(msg string, tok token.Token) {
-	msg += "\n" + s.getCurrentStatementLineFormatted()
-	s.error(msg, tok)
+	s.errorWithCode(CodeSemantic, msg, tok)
 }
 
-func (s *Semantic) warningWithStatement(msg string, tok token.Token) {
-	msg += "\n" + s.getCurrentStatementLineFormatted()
-	s.warning(msg, tok)
+// errorWithCode is errorWithStatement for the errors that have their own code
+func (s *Semantic) errorWithCode(code ErrorCode, msg string, tok token.Token) {
+	s.errorWithDetails(code, msg, "\n"+s.getCurrentStatementLineFormatted(), tok)
 }
 
-func (s *Semantic) errorWithExpression(msg string, expr ast.Expression) {
-	msg += "\n" + s.formatExpression(expr)
-	s.error(msg, expr.GetToken())
+func (s *Semantic) warningWithStatement(code ErrorCode, msg string, tok token.Token) {
+	message, hints := splitHints(msg)
+	text := msg + "\n" + s.getCurrentStatementLineFormatted()
+	s.Warnings = append(s.Warnings, &SemanticError{Span: tok.Span(), Code: code, Message: message, Hints: hints, text: text})
 }
 
-func (s *Semantic) warning(msg string, tok token.Token) {
-	s.Warnings = append(s.Errors, errors.New(fmt.Sprintf("[%d:%d] %s", tok.Line, tok.Position, msg)))
+func (s *Semantic) errorWithExpression(msg string, expr ast.Expression) {
+	s.errorOnSpan(CodeSemantic, msg, "\n"+s.formatExpression(expr), spanOf(expr))
 }
 
 func (s *Semantic) GetModule(name string) *Semantic {
@@ -145,9 +210,23 @@ func (s *Semantic) GetModule(name string) *Semantic {
 
 func (s *Semantic) Analyze(program *ast.Program) {
 	s.Root = program
+	if s.loader == nil {
+		s.loader = newLoader()
+		s.loader.dependencies = s.Dependencies
+		s.loader.searchPaths = s.SearchPaths
+		s.loader.stdDirectory = s.StdDirectory
+		s.key = s.absoluteFilePath()
+		if s.key == "" {
+			s.key = "main"
+		}
+
+		s.loader.root = s.key
+		s.loader.names[s.key] = s.key
+	}
 
 	s.predefineTypes(program.Statements)
 	s.fillTypes(program.Statements)
+	s.waitImports()
 	// Predefine functions so the order doesn't matter
 	s.predefineFunctions(program.Statements)
 
@@ -241,7 +320,7 @@ func (s *Semantic) analyzeStatement(statement ast.Statement) {
 
 	case *ast.BreakStatement:
 		if !s.insideBreakableBlock {
-			s.errorWithStatement("Unexpected break statement", statementType.Token)
+			s.errorWithCode(CodeMisplacedStatement, "Unexpected break statement", statementType.Token)
 		}
 		return
 
@@ -252,12 +331,16 @@ func (s *Semantic) analyzeStatement(statement ast.Statement) {
 
 	case *ast.ContinueStatement:
 		if !s.insideBreakableBlock {
-			s.errorWithStatement("Unexpected continue statement", statementType.Token)
+			s.errorWithCode(CodeMisplacedStatement, "Unexpected continue statement", statementType.Token)
 		}
 		return
+
+	case *ast.BadStatement:
+		// the parser already reported the syntax error
+		return
 	}
 
-	log.Fatalln("couldn't analyze statement: " + statement.String() + " ")
+	s.exitInternalError("couldn't analyze statement: " + statement.String())
 }
 
 func (s *Semantic) analyzeTypeDefinition(typeDef *ast.TypeDefinition) {
@@ -277,6 +360,7 @@ func (s *Semantic) analyzeExternStatement(extern *ast.ExternStatement) {
 	if !ok {
 		s.typeMismatchError(extern.String(), nil, extern.Token, &ctypes.Function{Name: "function"}, extern.Type)
 	}
+	s.functions[funk.Name] = extern
 	s.variables.Add(funk.Name, s.newType(funk))
 }
 
@@ -311,7 +395,7 @@ func (s *Semantic) analyzeForStatement(forStatement *ast.ForStatement) {
 	s.analyzeStatement(forStatement.InitializerStatement)
 
 	if forStatement.Condition == nil {
-		forStatement.Condition = &ast.Integer{Value: 1, Node: &node.Node{Type: ctypes.I1}}
+		forStatement.Condition = &ast.Integer{Value: 1, Node: &node.Node{Type: ctypes.I1, Token: forStatement.Token}}
 	}
 
 	condition := s.analyzeExpression(forStatement.Condition)
@@ -331,6 +415,25 @@ func (s *Semantic) analyzeForStatement(forStatement *ast.ForStatement) {
 
 func (s *Semantic) analyzeFunctionStatement(fun *ast.FunctionDeclarationStatement) {
 	s.analyzeFunctionType(fun.Token, fun.FunctionType, fun.Block)
+	if fun.FunctionType.IsMainFunction() {
+		s.analyzeMainFunctionSignature(fun)
+	}
+}
+
+// analyzeMainFunctionSignature checks that main is either 'func main()' or 'func main(argc i32, argv **i8)',
+// returning nothing or an i32 that is used as the exit code.
+func (s *Semantic) analyzeMainFunctionSignature(fun *ast.FunctionDeclarationStatement) {
+	mainType := fun.FunctionType
+	argv := &ctypes.Pointer{Inner: &ctypes.Pointer{Inner: ctypes.I8}}
+	validParameters := len(mainType.Parameters) == 0 ||
+		len(mainType.Parameters) == 2 && s.areTypesEqual(mainType.Parameters[0], ctypes.I32) && s.areTypesEqual(mainType.Parameters[1], argv)
+	validReturn := mainType.Return == ctypes.VoidType || s.areTypesEqual(mainType.Return, ctypes.I32)
+	if !validParameters || !validReturn {
+		s.errorWithStatement(
+			fmt.Sprintf("invalid signature for main '%s'\nHint: main should be declared as 'func main()' or 'func main(argc i32, argv **i8)', optionally returning an i32 exit code", mainType),
+			fun.Token,
+		)
+	}
 }
 
 func (s *Semantic) replaceAnonymousFunctionParameterTypes(fun *ctypes.Function) {
@@ -370,7 +473,7 @@ func (s *Semantic) analyzeFunctionType(functionToken token.Token, fun *ctypes.Fu
 	}
 
 	if !s.returns && fun.Return != ctypes.VoidType {
-		s.error("not all paths of the function '"+fun.String()+"' return a value", functionToken)
+		s.errorWithDetails(CodeMissingReturn, "not all paths of the function '"+fun.String()+"' return a value", "", functionToken)
 	}
 	fun.Return = s.UnwrapAnonymous(fun.Return)
 	s.returns = temporaryReturns
@@ -399,7 +502,7 @@ func (s *Semantic) analyzingInsideFunction() bool {
 
 func (s *Semantic) expectStatementInsideFunction(t token.Token) {
 	if !s.analyzingInsideFunction() {
-		s.errorWithStatement("Statement should be inside a function, but it's on the root of the source file.\nConsider moving this code to a function. If you pretended this code to run directly, wrap it inside func main()", t)
+		s.errorWithCode(CodeMisplacedStatement, "Statement should be inside a function, but it's on the root of the source file.\nConsider moving this code to a function. If you pretended this code to run directly, wrap it inside func main()", t)
 	}
 }
 
@@ -462,16 +565,18 @@ func (s *Semantic) analyzeStructStatement(statementType *ast.StructStatement) {
 		if anonymous, ok := unwrappedType.(*ctypes.Anonymous); ok {
 			definedType := s.UnwrapAnonymous(anonymous)
 			if t == anonymous && statementType.Type == definedType {
-				s.error(
+				s.errorWithDetails(CodeRecursiveType,
 					"can't analyze field for this struct because you are referencing a struct that has been later defined or it's a recursive type.\nHint: maybe define this field's type before this type or fix recursive type",
+					"",
 					statementType.Token,
 				)
 				return
 			}
 			s.swapTypes(t, definedType)
 		} else if statementType.Type == t {
-			s.error(
+			s.errorWithDetails(CodeRecursiveType,
 				"Recursive type detected",
+				"",
 				statementType.Token,
 			)
 		}
@@ -489,16 +594,18 @@ func (s *Semantic) analyzeUnionStatement(statementType *ast.UnionStatement) {
 		if anonymous, ok := unwrappedType.(*ctypes.Anonymous); ok {
 			definedType := s.UnwrapAnonymous(anonymous)
 			if t == anonymous && statementType.Type == definedType {
-				s.error(
+				s.errorWithDetails(CodeRecursiveType,
 					"can't analyze field for this union because you are referencing a union that has been later defined or it's a recursive type.\nHint: maybe define this field's type before this type or fix recursive type",
+					"",
 					statementType.Token,
 				)
 				return
 			}
 			s.swapTypes(t, definedType)
 		} else if statementType.Type == t {
-			s.error(
+			s.errorWithDetails(CodeRecursiveType,
 				"Recursive type detected",
+				"",
 				statementType.Token,
 			)
 		}
@@ -512,7 +619,7 @@ func (s *Semantic) analyzeBuiltinCall(call *ast.BuiltinCall) ctypes.Type {
 		call.Type = t
 		return t
 	}
-	s.errorWithStatement("unknown builtin call", call.Token)
+	s.errorWithCode(CodeBuiltin, "unknown builtin call", call.Token)
 	return ctypes.TODO()
 }
 
@@ -522,7 +629,7 @@ func (s *Semantic) analyzeDeclarationStatement(declaration *ast.DeclarationState
 	ctype := s.analyzeExpression(declaration.Expression)
 
 	if list, ok := ctype.(*ctypes.TypeList); ok {
-		s.errorWithStatement(fmt.Sprintf("the expression returns %d values, but you only declared 1", len(list.Types)), declaration.Token)
+		s.errorWithCode(CodeValueCount, fmt.Sprintf("the expression returns %d values, but you only declared 1", len(list.Types)), declaration.Token)
 		return
 	}
 
@@ -549,6 +656,7 @@ func (s *Semantic) analyzeDeclarationStatement(declaration *ast.DeclarationState
 		declaration.Type = declType
 		declaredType := s.newType(declType)
 		declaredType.IsConstant = declaration.Constant
+		s.evaluateConstantDeclaration(declaration, declaredType)
 		s.variables.Add(declaration.Name, declaredType)
 		return
 	}
@@ -556,6 +664,7 @@ func (s *Semantic) analyzeDeclarationStatement(declaration *ast.DeclarationState
 	declaration.Type = ctype
 	declaredType := s.newType(ctype)
 	declaredType.IsConstant = declaration.Constant
+	s.evaluateConstantDeclaration(declaration, declaredType)
 	s.variables.Add(declaration.Name, declaredType)
 }
 
@@ -600,13 +709,25 @@ func (s *Semantic) UnwrapAnonymous(t ctypes.Type) ctypes.Type {
 			if typesDefined == "" {
 				typesDefined = "No types defined in the module."
 			}
-			s.errorWithStatement("Couldn't guess type "+ast.RetrieveID(anonymous.Name)+", maybe spelt the type wrong? These are the defined types in the module"+" "+ast.RetrieveID(module)+":\n"+typesDefined, s.currentStatementBeingAnalyzed.GetToken())
+			s.errorWithCode(CodeUndefined, "Couldn't guess type "+ast.RetrieveID(anonymous.Name)+", maybe spelt the type wrong? These are the defined types in the module"+" "+ast.RetrieveID(module)+":\n"+typesDefined, s.currentStatementBeingAnalyzed.GetToken())
 			return ctypes.TODO()
 		}
 
 		return t
 	}
 
+	// Functions that return multiple values need their types unwrapped on the module that declares
+	// them, so other modules don't look for them on their own scope. The list might be shared with
+	// modules that are being analyzed at the same time, so a new one is returned.
+	if list, ok := t.(*ctypes.TypeList); ok {
+		unwrapped := &ctypes.TypeList{Types: make([]ctypes.Type, len(list.Types))}
+		for i := range list.Types {
+			unwrapped.Types[i] = s.UnwrapAnonymous(list.Types[i])
+		}
+
+		return unwrapped
+	}
+
 	return t
 }
 
@@ -642,7 +763,7 @@ func (s *Semantic) swapTypes(t ctypes.Type, toSwap ctypes.Type) ctypes.Type {
 	if toSwap == ctypes.TODO() {
 		trueType := s.UnwrapAnonymous(t)
 		if _, ok := trueType.(*ctypes.Anonymous); (ok && trueType == t) || trueType == nil {
-			s.errorWithStatement("unknown type "+t.String(), s.currentStatementBeingAnalyzed.GetToken())
+			s.errorWithCode(CodeUndefined, "unknown type "+t.String(), s.currentStatementBeingAnalyzed.GetToken())
 		}
 		return trueType
 	}
@@ -717,8 +838,17 @@ func (s *Semantic) areTypesEqual(first, second ctypes.Type) bool {
 		return fArray.Length == sArray.Length && s.areTypesEqual(fArray.Inner, sArray.Inner)
 	}
 
+	if fVector, ok := first.(*ctypes.Vector); ok {
+		sVector, ok := second.(*ctypes.Vector)
+		if !ok {
+			return false
+		}
+
+		return fVector.Length == sVector.Length && s.areTypesEqual(fVector.Inner, sVector.Inner)
+	}
+
 	if ctypes.IsUnion(first) || ctypes.IsUnion(second) {
-		s.warningWithStatement("we can't coerce into a union here,\ntry putting this statement on its own declaration or assignment to fix this warning", s.currentStatementBeingAnalyzed.GetToken())
+		s.warningWithStatement(CodeUnionCoercion, "we can't coerce into a union here,\ntry putting this statement on its own declaration or assignment to fix this warning", s.currentStatementBeingAnalyzed.GetToken())
 	}
 
 	return false
@@ -772,6 +902,10 @@ func (s *Semantic) areTypesEqualIncludingUnions(first, second ctypes.Type) bool
 		return fArray.Length == sArray.Length && s.areTypesEqualIncludingUnions(fArray.Inner, sArray.Inner)
 	}
 
+	if ctypes.IsVector(first) {
+		return s.areTypesEqual(first, second)
+	}
+
 	return false
 }
 
@@ -811,9 +945,11 @@ func (s *Semantic) analyzeExpression(expression ast.Expression) ctypes.Type {
 		stringLiteralType := &ctypes.Pointer{Inner: ctypes.I8}
 		expressionType.Type = stringLiteralType
 		return stringLiteralType
+	case *ast.BadExpression:
+		return expressionType.Type
 
 	default:
-		log.Fatalln("couldn't analyze expression: " + expressionType.String())
+		s.exitInternalError("couldn't analyze expression: " + expressionType.String())
 	}
 	return nil
 }
@@ -853,7 +989,7 @@ func (s *Semantic) retrieveModule(moduleName string) *Semantic {
 	module, ok := s.modules[moduleName]
 
 	if !ok {
-		s.errorWithStatement("undefined module "+ast.RetrieveID(moduleName), s.currentStatementBeingAnalyzed.GetToken())
+		s.errorWithCode(CodeUndefined, "undefined module "+ast.RetrieveID(moduleName), s.currentStatementBeingAnalyzed.GetToken())
 		return s
 	}
 
@@ -899,7 +1035,7 @@ func (s *Semantic) analyzeStructLiteral(structLiteral *ast.StructLiteral) ctypes
 	structType, ok := s.UnwrapAnonymous(possibleStructType).(*ctypes.Struct)
 
 	if !ok {
-		s.errorWithStatement("undefined struct "+ast.RetrieveID(structLiteral.Name)+": "+structLiteral.String(), structLiteral.Token)
+		s.errorWithCode(CodeUndefined, "undefined struct "+ast.RetrieveID(structLiteral.Name)+": "+structLiteral.String(), structLiteral.Token)
 		return ctypes.TODO()
 	}
 
@@ -913,7 +1049,7 @@ func (s *Semantic) analyzeStructLiteral(structLiteral *ast.StructLiteral) ctypes
 	for _, value := range structLiteral.Values {
 		index, ok := paramMap[value.Name]
 		if !ok {
-			s.errorWithStatement("undefined attribute on struct literal "+value.Name, structLiteral.Token)
+			s.errorWithCode(CodeUndefined, "undefined attribute on struct literal "+value.Name, structLiteral.Token)
 		}
 		expression := s.analyzeExpression(value.Expression)
 		if !s.areTypesEqualIncludingUnions(structType.Fields[index], expression) {
@@ -944,8 +1080,19 @@ func (s *Semantic) analyzeArrayLiteral(arrayLiteral *ast.ArrayLiteral) ctypes.Ty
 }
 
 func (s *Semantic) analyzeFunctionCall(call *ast.Call) ctypes.Type {
-	s.throwInvalidOperationForConstant("can't call non constant functions for a constant expression", call)
+	// comptime functions can be called on constant expressions, the rest of functions aren't constants
+	expectConstantExpression := s.expectConstantExpression
+	s.expectConstantExpression = false
 	possibleFuncType := s.analyzeExpression(call.Left)
+	s.expectConstantExpression = expectConstantExpression
+	if funcType, ok := possibleFuncType.(*ctypes.Function); !ok || !funcType.Comptime {
+		message := "can't call non constant functions for a constant expression"
+		if _, _, err := s.ComptimeFunction(call.Left); err != nil {
+			message = err.Error()
+		}
+
+		s.throwInvalidOperationForConstant(message+"\n", call)
+	}
 
 	// it is possible that we are retrieving a sturct access with function
 	var funcType *ctypes.Function
@@ -971,7 +1118,7 @@ func (s *Semantic) analyzeFunctionCall(call *ast.Call) ctypes.Type {
 
 	if len(call.Parameters) != len(funcType.Parameters) && !funcType.InfiniteParameters {
 
-		s.errorWithStatement(fmt.Sprintf("mismatch number of parameters, expected %d, got %d", len(funcType.Parameters), len(call.Parameters)), call.Token)
+		s.errorWithCode(CodeValueCount, fmt.Sprintf("mismatch number of parameters, expected %d, got %d", len(funcType.Parameters), len(call.Parameters)), call.Token)
 	}
 
 	for i, param := range call.Parameters {
@@ -1012,17 +1159,17 @@ func (s *Semantic) analyzeIdentifier(identifier *ast.Identifier) ctypes.Type {
 		}
 
 		if !identifierType.IsConstant && s.expectConstantExpression {
-			s.errorWithStatement("this expression should be constant and '"+identifier.String()+"' is not a constant variable", identifier.Token)
+			s.errorWithCode(CodeNotConstant, "this expression should be constant and '"+identifier.String()+"' is not a constant variable", identifier.Token)
 		}
 
 		if identifierType.IsConstant && s.expectNonConstantExpression {
-			s.errorWithStatement("you are trying to mutate a constant, '"+identifier.String()+"' is a constant variable", identifier.Token)
+			s.errorWithCode(CodeConstantMutation, "you are trying to mutate a constant, '"+identifier.String()+"' is a constant variable", identifier.Token)
 		}
 
 		return identifierType.Type
 	}
 
-	s.errorWithStatement("undefined variable "+ast.RetrieveID(identifier.Name), identifier.Token)
+	s.errorWithCode(CodeUndefined, "undefined variable "+ast.RetrieveID(identifier.Name), identifier.Token)
 	return ctypes.TODO()
 }
 
@@ -1122,7 +1269,7 @@ func (s *Semantic) analyzeModuleAccess(module *Semantic, binaryOp *ast.BinaryOpe
 	// Reassign identifier to the new name
 	identifier.Name = name
 	if accessedElement == nil {
-		s.errorWithStatement(ast.RetrieveID(identifier.Name)+" does not exist in the specified module", binaryOp.Token)
+		s.errorWithCode(CodeUndefined, ast.RetrieveID(identifier.Name)+" does not exist in the specified module", binaryOp.Token)
 		return ctypes.TODO()
 	}
 
@@ -1220,7 +1367,7 @@ func (s *Semantic) analyzeFieldAccess(binaryOperation *ast.BinaryOperation) ctyp
 		// Maybe the user wanted to access a member function, on this module or another
 		variable := m.variables.Get(m.TranslateName(namePlusId))
 		if variable == nil || !ctypes.IsFunction(variable.Type) {
-			s.errorWithStatement("unknown field "+binaryOperation.String(), binaryOperation.Token)
+			s.errorWithCode(CodeUndefined, "unknown field "+binaryOperation.String(), binaryOperation.Token)
 			return ctypes.TODO()
 		}
 
@@ -1254,69 +1401,156 @@ func (s *Semantic) isArithmetic(op ops.Operation) bool {
 //     we won't find type discrepancies, which is good because really, it's the same type.
 //   - This works because let's remember that string names in definitions are <name_put_by_the_user> '-' <random_id_set_by_the_parser>.
 //     the random id is located in the *Semantic.Root attribute, so we can use it to create or parse names.
+// analyzeImport starts analyzing the imported module, the module is analyzed concurrently
+// with the rest of imports of this file and it's ready to be used after waitImports.
 func (s *Semantic) analyzeImport(importStatement *ast.ImportStatement) {
+	// If a generic type refers to an imported module, it needs to be analyzed first
+	for _, t := range importStatement.Types {
+		if referencesModule(t) {
+			s.waitImports()
+			break
+		}
+	}
+
 	types := make([]ctypes.Type, 0, len(importStatement.Types))
 	for _, t := range importStatement.Types {
 		t = s.replaceAnonymous(t)
 		types = append(types, s.UnwrapAnonymous(t))
 	}
 
-	// TODO this is not correct, should be relative path of current file
-	currentPath, err := os.Getwd()
-	if err != nil {
-		s.Errors = append(s.Errors, err)
-		currentPath = "/"
+	currentPathPlusImport, ok := s.resolveImport(importStatement)
+	if !ok {
+		return
 	}
-	// Join total path from the compiler standpoint to the context directory path
-	currentPath = path.Join(currentPath, s.ContextDirectoryPath)
-	// Join that path with import path, having the complete path to the file
-	currentPathPlusImport := path.Join(currentPath, importStatement.Path.Value)
+
 	hashType := strings.Builder{}
 	for _, t := range types {
 		hashType.WriteByte(',')
 		hashType.WriteString(t.String())
 	}
-	endHash := currentPathPlusImport + hashType.String()
-	if existingSemantic, ok := paths[endHash]; ok {
-		s.modules[importStatement.Name] = existingSemantic
-		// We need this for methods that are referenced as
-		// <struct_instance>.<method_that_contains_as_first_param_the_instance>(...)
-		s.modules[existingSemantic.Root.ID] = existingSemantic
+	typesKey := hashType.String()
+	endHash := currentPathPlusImport + typesKey
+	future, isNew, cycle := s.loader.request(s.key, endHash, currentPathPlusImport)
+	if cycle != nil {
+		s.errorWithCode(CodeImport, formatImportCycle(cycle), importStatement.Token)
 		return
 	}
-	text, err := os.ReadFile(currentPathPlusImport)
+
+	if isNew {
+		go s.loader.analyzeModule(future, importStatement, endHash, currentPathPlusImport, types, typesKey)
+	}
+
+	s.pendingImports = append(s.pendingImports, pendingImport{statement: importStatement, future: future})
+}
+
+// analyzeModule reads, parses and analyzes an imported module, it runs on its own goroutine.
+func (l *loader) analyzeModule(
+	future *moduleFuture,
+	importStatement *ast.ImportStatement,
+	key, filePath string,
+	types []ctypes.Type,
+	typesKey string,
+) {
+	l.acquire()
+	defer l.release()
+	defer close(future.done)
+
+	text, err := os.ReadFile(filePath)
 	if err != nil {
-		s.errorWithStatement(fmt.Sprintf("error importing file with path %s: %s", importStatement.Path, err.Error()), importStatement.Token)
+		future.failure = fmt.Sprintf("error importing file with path %s: %s", importStatement.Path, err.Error())
 		return
 	}
 
-	l := lexer.New(string(text))
-	p := parser.New(l)
+	lex := lexer.NewFile(filePath, string(text))
+	p := parser.New(lex)
+	p.ID = ModuleID(filePath, text, typesKey)
 	p.TypeParameters = types
 	tree := p.Parse()
 	if len(p.Errors) > 0 {
-		s.errorWithStatement("error parsing file imported on path "+importStatement.Path.String(), importStatement.Token)
-		s.Errors = append(s.Errors, p.Errors...)
+		future.failure = "error parsing file imported on path " + importStatement.Path.String()
+		future.errors = p.Errors
 		return
 	}
 
 	internalSemantic := New()
-	// Get the local directory where the user is importing
-	dir, _ := path.Split(importStatement.Path.Value)
-	// Join with the context path
-	internalSemantic.ContextDirectoryPath = path.Join(s.ContextDirectoryPath, dir)
+	internalSemantic.loader = l
+	internalSemantic.key = key
+	internalSemantic.holdsWorker = true
+	internalSemantic.FilePath = filePath
+	internalSemantic.ContextDirectoryPath = path.Dir(filePath)
 	internalSemantic.Analyze(tree)
 	if len(internalSemantic.Errors) > 0 {
-		s.errorWithStatement("error analyzing file imported on path "+importStatement.Path.String(), importStatement.Token)
-		s.Errors = append(s.Errors, internalSemantic.Errors...)
+		future.failure = "error analyzing file imported on path " + importStatement.Path.String()
+		future.errors = internalSemantic.Errors
 		return
 	}
 
-	paths[endHash] = internalSemantic
-	// We need this for methods that are referenced as
-	// <struct_instance>.<method_that_contains_as_first_param_the_instance>(...)
-	s.modules[internalSemantic.Root.ID] = internalSemantic
-	s.modules[importStatement.Name] = internalSemantic
+	internalSemantic.ComputeChecksum(text, typesLayout(types))
+	future.semantic = internalSemantic
+}
+
+// waitImports waits for every import that is being analyzed and makes them available to this module.
+func (s *Semantic) waitImports() {
+	if len(s.pendingImports) == 0 {
+		return
+	}
+
+	pendingImports := s.pendingImports
+	s.pendingImports = nil
+
+	// Let other modules use our worker while we wait
+	if s.holdsWorker {
+		s.loader.release()
+	}
+
+	for _, pending := range pendingImports {
+		<-pending.future.done
+	}
+
+	if s.holdsWorker {
+		s.loader.acquire()
+	}
+
+	currentStatement := s.currentStatementBeingAnalyzed
+	defer func() {
+		s.currentStatementBeingAnalyzed = currentStatement
+	}()
+
+	for _, pending := range pendingImports {
+		s.currentStatementBeingAnalyzed = pending.statement
+		if pending.future.semantic == nil {
+			s.errorWithCode(CodeImport, pending.future.failure, pending.statement.Token)
+			s.Errors = append(s.Errors, pending.future.errors...)
+			continue
+		}
+
+		module := pending.future.semantic
+		// We need this for methods that are referenced as
+		// <struct_instance>.<method_that_contains_as_first_param_the_instance>(...)
+		s.modules[module.Root.ID] = module
+		s.modules[pending.statement.Name] = module
+	}
+}
+
+func referencesModule(t ctypes.Type) bool {
+	switch t := t.(type) {
+	case *ctypes.Anonymous:
+		return len(t.Modules) > 0
+	case *ctypes.Pointer:
+		return referencesModule(t.Inner)
+	case *ctypes.Array:
+		return referencesModule(t.Inner)
+	case *ctypes.Function:
+		for _, parameter := range t.Parameters {
+			if referencesModule(parameter) {
+				return true
+			}
+		}
+
+		return t.Return != nil && referencesModule(t.Return)
+	}
+
+	return false
 }
 
 func (s *Semantic) analyzeExpressionBlock(blockExpression *ast.ExpressionBlock) ctypes.Type {
@@ -1331,7 +1565,7 @@ func (s *Semantic) analyzeExpressionBlock(blockExpression *ast.ExpressionBlock)
 	s.currentExpectedReturnType = t
 	s.analyzeBlock(blockExpression.Block)
 	if !s.returns {
-		s.errorWithStatement(fmt.Sprintf("not all paths of expression block return type %q", t.String()), blockExpression.Token)
+		s.errorWithCode(CodeMissingReturn, fmt.Sprintf("not all paths of expression block return type %q", t.String()), blockExpression.Token)
 		return ctypes.TODO()
 	}
 
@@ -1371,6 +1605,10 @@ func (s *Semantic) analyzeArithmetic(binaryOperation *ast.BinaryOperation) ctype
 		s.typeMismatchBlameArithmeticExpressionError(binaryOperation.String(), binaryOperation, binaryOperation.Token, true)
 	}
 
+	if vector, isVector := left.(*ctypes.Vector); isVector {
+		return s.analyzeVectorArithmetic(binaryOperation, vector)
+	}
+
 	if binaryOperation.Operation.IsComparison() {
 		if ctypes.IsArray(left) {
 			s.errorWithStatement("Candice can't compare arrays for you, you should try to cast them to a pointer and then compare again if you want a pointer comparison", binaryOperation.Token)
@@ -1449,12 +1687,12 @@ func (s *Semantic) analyzeMultipleDeclarationStatement(m *ast.MultipleDeclaratio
 	if typeListInstance, isList := possibleTypeList.(*ctypes.TypeList); isList {
 		typeList = typeListInstance
 	} else {
-		s.errorWithStatement("you can't declare multiple variables with this expression, got the following type: "+possibleTypeList.String(), m.Token)
+		s.errorWithCode(CodeValueCount, "you can't declare multiple variables with this expression, got the following type: "+possibleTypeList.String(), m.Token)
 		return
 	}
 
 	if len(typeList.Types) != len(m.Names) {
-		s.errorWithStatement(fmt.Sprintf("the expression returns %d values, but you only declared %d", len(typeList.Types), len(m.Names)), m.Token)
+		s.errorWithCode(CodeValueCount, fmt.Sprintf("the expression returns %d values, but you only declared %d", len(typeList.Types), len(m.Names)), m.Token)
 		return
 	}
 
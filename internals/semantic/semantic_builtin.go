@@ -1,6 +1,8 @@
 package semantic
 
 import (
+	"strings"
+
 	"github.com/gabivlj/candice/internals/ast"
 	"github.com/gabivlj/candice/internals/ctypes"
 )
@@ -11,7 +13,7 @@ func (s *Semantic) analyzeAddCompilerFlag(addCompilerFlag *ast.BuiltinCall) ctyp
 		s.expectConstantExpression = true
 		ty := s.analyzeExpression(param)
 		if !s.areTypesEqual(ty, ctypes.NewPointer(ctypes.I8)) {
-			s.errorWithStatement("expected constant string literal on `add_compiler_flag`", addCompilerFlag.Token)
+			s.errorWithCode(CodeBuiltin, "expected constant string literal on `add_compiler_flag`", addCompilerFlag.Token)
 		}
 	}
 	s.expectConstantExpression = prevExpectConstantExpression
@@ -24,6 +26,10 @@ func (s *Semantic) analyzeCast(castCall *ast.BuiltinCall) ctypes.Type {
 	currentType := s.UnwrapAnonymous(s.analyzeExpression(castCall.Parameters[0]))
 	toType := s.UnwrapAnonymous(castCall.TypeParameters[0])
 	castCall.TypeParameters[0] = toType
+	if ctypes.IsVector(currentType) || ctypes.IsVector(toType) {
+		return s.analyzeVectorCast(castCall, currentType, toType)
+	}
+
 	if (ctypes.IsPointer(currentType) || ctypes.IsArray(currentType) || ctypes.IsNumeric(currentType)) &&
 		(ctypes.IsPointer(toType) || ctypes.IsArray(toType) || ctypes.IsNumeric(toType)) {
 		castCall.Type = toType
@@ -33,19 +39,20 @@ func (s *Semantic) analyzeCast(castCall *ast.BuiltinCall) ctypes.Type {
 	// This handles types that are equal, even unions
 	if s.areTypesEqual(currentType, toType) {
 		if ctypes.IsUnion(toType) || ctypes.IsUnion(currentType) {
-			s.errorWithStatement("it seems you are trying to cast into a union or cast a union,\nat the moment that is not supported in Candice.\nYou can find a workaround by setting in a variable declaration the selected expression.", castCall.Token)
+			s.errorWithCode(CodeBuiltin, "it seems you are trying to cast into a union or cast a union,\nat the moment that is not supported in Candice.\nYou can find a workaround by setting in a variable declaration the selected expression.", castCall.Token)
 			return ctypes.TODO()
 		}
 	}
 
-	s.error("can't cast "+currentType.String()+" to "+toType.String(), castCall.Token)
+	s.builtinError("can't cast "+currentType.String()+" to "+toType.String(), castCall.Token)
 	return ctypes.TODO()
 }
 
 func (s *Semantic) analyzeAlloc(allocCall *ast.BuiltinCall) ctypes.Type {
 	s.throwInvalidOperationForConstant("you can't allocate on constants", allocCall)
-	t := allocCall.TypeParameters[0]
-	s.replaceAnonymous(t)
+	// the compiler needs the true type to know its size
+	t := s.replaceAnonymous(allocCall.TypeParameters[0])
+	allocCall.TypeParameters[0] = t
 	expr := s.analyzeExpression(allocCall.Parameters[0])
 	if !ctypes.IsNumeric(expr) {
 		s.typeMismatchError(allocCall.String(), allocCall.Parameters[0], allocCall.Token, ctypes.I32, expr)
@@ -70,11 +77,11 @@ func (s *Semantic) analyzePrintln(printCall *ast.BuiltinCall) ctypes.Type {
 func (s *Semantic) analyzeFree(freeCall *ast.BuiltinCall) ctypes.Type {
 	s.throwInvalidOperationForConstant("you can't free on constants", freeCall)
 	if len(freeCall.Parameters) != 1 {
-		s.error("expected one parameter for free builtin call", freeCall.Token)
+		s.builtinError("expected one parameter for free builtin call", freeCall.Token)
 		return ctypes.TODO()
 	}
 	if !ctypes.IsPointer(s.UnwrapAnonymous(s.analyzeExpression(freeCall.Parameters[0]))) {
-		s.error("expected pointer type for free call", freeCall.Token)
+		s.builtinError("expected pointer type for free call", freeCall.Token)
 	}
 	return ctypes.VoidType
 }
@@ -82,14 +89,14 @@ func (s *Semantic) analyzeFree(freeCall *ast.BuiltinCall) ctypes.Type {
 func (s *Semantic) analyzeRealloc(reallocCall *ast.BuiltinCall) ctypes.Type {
 	s.throwInvalidOperationForConstant("you can't allocate on constants", reallocCall)
 	if len(reallocCall.Parameters) != 2 {
-		s.error("expected two parameters for realloc builtin call", reallocCall.Token)
+		s.builtinError("expected two parameters for realloc builtin call", reallocCall.Token)
 		return ctypes.TODO()
 	}
 
 	t := s.UnwrapAnonymous(s.analyzeExpression(reallocCall.Parameters[0]))
 
 	if !ctypes.IsPointer(t) {
-		s.error("expected pointer type for realloc call", reallocCall.Token)
+		s.builtinError("expected pointer type for realloc call", reallocCall.Token)
 	}
 
 	secondParameter := s.analyzeExpression(reallocCall.Parameters[1])
@@ -98,6 +105,11 @@ func (s *Semantic) analyzeRealloc(reallocCall *ast.BuiltinCall) ctypes.Type {
 		s.typeMismatchError(reallocCall.String(), reallocCall.Parameters[1], reallocCall.Token, ctypes.I64, secondParameter)
 	}
 
+	// the compiler needs the true type of the elements to know their size
+	if ptr, ok := t.(*ctypes.Pointer); ok {
+		t = &ctypes.Pointer{Inner: s.UnwrapAnonymous(ptr.Inner)}
+	}
+
 	reallocCall.Type = t
 
 	return t
@@ -105,7 +117,7 @@ func (s *Semantic) analyzeRealloc(reallocCall *ast.BuiltinCall) ctypes.Type {
 
 func (s *Semantic) analyzeSizeOf(sizeOfCall *ast.BuiltinCall) ctypes.Type {
 	if len(sizeOfCall.TypeParameters) != 1 {
-		s.error("expected one type parameter for sizeOf builtin call", sizeOfCall.Token)
+		s.builtinError("expected one type parameter for sizeOf builtin call", sizeOfCall.Token)
 		return ctypes.TODO()
 	}
 	sizeOfCall.TypeParameters[0] = s.UnwrapAnonymous(sizeOfCall.TypeParameters[0])
@@ -116,15 +128,116 @@ func (s *Semantic) analyzeSizeOf(sizeOfCall *ast.BuiltinCall) ctypes.Type {
 func (s *Semantic) analyzeAsm(asmCall *ast.BuiltinCall) ctypes.Type {
 	s.throwInvalidOperationForConstant("you can't execute assembly on constant variables", asmCall)
 	if len(asmCall.Parameters) == 0 {
-		s.error("expected a constant string literal on asm builtin call", asmCall.Token)
+		s.builtinError("expected a constant string literal on asm builtin call", asmCall.Token)
 		return ctypes.TODO()
 	}
 
 	_, isString := asmCall.Parameters[0].(*ast.StringLiteral)
 	if !isString {
-		s.error("expected a constant string literal on asm builtin call, got: "+asmCall.Parameters[0].String(), asmCall.Token)
+		s.builtinError("expected a constant string literal on asm builtin call, got: "+asmCall.Parameters[0].String(), asmCall.Token)
 		return ctypes.TODO()
 	}
 
 	return s.UnwrapAnonymous(asmCall.TypeParameters[0])
 }
+
+// memory orderings that each atomic builtin accepts, they are written as identifiers: @atomic_load(&x, acquire)
+var atomicOrderings = map[string]map[string]bool{
+	"atomic_load":  {"relaxed": true, "acquire": true, "seq_cst": true},
+	"atomic_store": {"relaxed": true, "release": true, "seq_cst": true},
+	"atomic_add":   {"relaxed": true, "acquire": true, "release": true, "acq_rel": true, "seq_cst": true},
+	"atomic_cas":   {"relaxed": true, "acquire": true, "release": true, "acq_rel": true, "seq_cst": true},
+	"fence":        {"acquire": true, "release": true, "acq_rel": true, "seq_cst": true},
+}
+
+func (s *Semantic) analyzeAtomicOrdering(call *ast.BuiltinCall, ordering ast.Expression) {
+	identifier, isIdentifier := ordering.(*ast.Identifier)
+	if !isIdentifier || !atomicOrderings[call.Name][identifier.Token.Literal] {
+		s.builtinError("invalid memory ordering "+ordering.String()+" for "+call.Name+", expected one of: "+atomicOrderingNames(call.Name), call.Token)
+	}
+}
+
+func atomicOrderingNames(name string) string {
+	names := make([]string, 0, len(atomicOrderings[name]))
+	for _, ordering := range []string{"relaxed", "acquire", "release", "acq_rel", "seq_cst"} {
+		if atomicOrderings[name][ordering] {
+			names = append(names, ordering)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// analyzeAtomicPointer checks that the first parameter of an atomic builtin points to an integer,
+// or to a pointer if the operation allows them, and returns the type that it points to.
+func (s *Semantic) analyzeAtomicPointer(call *ast.BuiltinCall, allowPointers bool) ctypes.Type {
+	t := s.UnwrapAnonymous(s.analyzeExpression(call.Parameters[0]))
+	ptr, isPointer := t.(*ctypes.Pointer)
+	if !isPointer {
+		s.builtinError("expected a pointer as the first parameter of "+call.Name+", got: "+t.String(), call.Token)
+		return ctypes.TODO()
+	}
+
+	inner := s.UnwrapAnonymous(ptr.Inner)
+	// atomics need at least a byte, so booleans don't count as integers here
+	isInteger := ctypes.IsInteger(inner) && inner != ctypes.I1
+	if !isInteger && !(allowPointers && ctypes.IsPointer(inner)) {
+		expected := "integers"
+		if allowPointers {
+			expected = "integers and pointers"
+		}
+
+		s.builtinError(call.Name+" only works on "+expected+", got: "+ptr.Inner.String(), call.Token)
+		return ctypes.TODO()
+	}
+
+	return inner
+}
+
+func (s *Semantic) analyzeAtomicValue(call *ast.BuiltinCall, expected ctypes.Type, parameter ast.Expression) {
+	t := s.analyzeExpression(parameter)
+	if !s.areTypesEqual(expected, t) {
+		s.typeMismatchError(call.String(), parameter, call.Token, expected, t)
+	}
+}
+
+func (s *Semantic) analyzeAtomicLoad(call *ast.BuiltinCall) ctypes.Type {
+	s.throwInvalidOperationForConstant("you can't use atomics on constants", call)
+	t := s.analyzeAtomicPointer(call, true)
+	s.analyzeAtomicOrdering(call, call.Parameters[1])
+	return t
+}
+
+func (s *Semantic) analyzeAtomicStore(call *ast.BuiltinCall) ctypes.Type {
+	s.throwInvalidOperationForConstant("you can't use atomics on constants", call)
+	t := s.analyzeAtomicPointer(call, true)
+	s.analyzeAtomicValue(call, t, call.Parameters[1])
+	s.analyzeAtomicOrdering(call, call.Parameters[2])
+	return ctypes.VoidType
+}
+
+// analyzeAtomicAdd returns the type of the value that was stored before adding
+func (s *Semantic) analyzeAtomicAdd(call *ast.BuiltinCall) ctypes.Type {
+	s.throwInvalidOperationForConstant("you can't use atomics on constants", call)
+	t := s.analyzeAtomicPointer(call, false)
+	s.analyzeAtomicValue(call, t, call.Parameters[1])
+	s.analyzeAtomicOrdering(call, call.Parameters[2])
+	return t
+}
+
+// analyzeAtomicCas analyzes a compare and swap: @atomic_cas(ptr, expected, new, ordering),
+// it returns whether the value was swapped.
+func (s *Semantic) analyzeAtomicCas(call *ast.BuiltinCall) ctypes.Type {
+	s.throwInvalidOperationForConstant("you can't use atomics on constants", call)
+	t := s.analyzeAtomicPointer(call, true)
+	s.analyzeAtomicValue(call, t, call.Parameters[1])
+	s.analyzeAtomicValue(call, t, call.Parameters[2])
+	s.analyzeAtomicOrdering(call, call.Parameters[3])
+	return ctypes.I1
+}
+
+func (s *Semantic) analyzeFence(call *ast.BuiltinCall) ctypes.Type {
+	s.throwInvalidOperationForConstant("you can't use atomics on constants", call)
+	s.analyzeAtomicOrdering(call, call.Parameters[0])
+	return ctypes.VoidType
+}
@@ -0,0 +1,84 @@
+package semantic
+
+import (
+	"errors"
+
+	"github.com/gabivlj/candice/internals/ast"
+	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/eval"
+	"github.com/gabivlj/candice/internals/ops"
+)
+
+// constantEnvironment lets the evaluator use the constants that are in scope
+type constantEnvironment struct {
+	s *Semantic
+
+	// global is true for the bodies of comptime functions, they can only see global constants
+	global bool
+}
+
+func (c constantEnvironment) Constant(identifier *ast.Identifier) (eval.Value, bool) {
+	variable := c.s.variables.Get(identifier.Name)
+	if c.global {
+		// locals of the function that is being analyzed are added after the globals
+		if variable = c.s.variables.Outermost(identifier.Name); variable != nil && variable.parentFunction != nil {
+			return nil, false
+		}
+	}
+
+	if variable == nil || !variable.IsConstant || variable.Value == nil {
+		return nil, false
+	}
+
+	return variable.Value, true
+}
+
+func (c constantEnvironment) Type(t ctypes.Type) (ctypes.Type, bool) {
+	t = c.s.UnwrapAnonymous(t)
+	if _, isAnonymous := t.(*ctypes.Anonymous); isAnonymous || t == ctypes.TODO() {
+		return nil, false
+	}
+
+	return t, true
+}
+
+func (c constantEnvironment) Function(callee ast.Expression) (*ast.FunctionDeclarationStatement, eval.Environment, error) {
+	return c.s.ComptimeFunction(callee)
+}
+
+// ComptimeFunction returns the comptime function that the callee references and the environment
+// to run it, the callee can be a function of this module or of an imported one like module.function.
+func (s *Semantic) ComptimeFunction(callee ast.Expression) (*ast.FunctionDeclarationStatement, eval.Environment, error) {
+	module := s
+	if access, isAccess := callee.(*ast.BinaryOperation); isAccess && access.Operation == ops.Dot {
+		if identifier, isIdentifier := access.Left.(*ast.Identifier); isIdentifier && s.modules[identifier.Name] != nil {
+			module = s.modules[identifier.Name]
+			callee = access.Right
+		}
+	}
+
+	identifier, isIdentifier := callee.(*ast.Identifier)
+	if !isIdentifier {
+		return nil, nil, errors.New("can't call " + callee.String() + " at compile time, only comptime functions can be called")
+	}
+
+	name := module.TranslateName(identifier.Name)
+	function, err := eval.ComptimeFunction(ast.RetrieveID(name), module.functions[name])
+	return function, constantEnvironment{s: module, global: true}, err
+}
+
+// evaluateConstantDeclaration evaluates the value of a constant, so other constants can use it
+func (s *Semantic) evaluateConstantDeclaration(declaration *ast.DeclarationStatement, declaredType *SemanticType) {
+	// if the type is unknown the expression already has errors
+	if !declaration.Constant || declaredType.Type == ctypes.TODO() {
+		return
+	}
+
+	value := eval.Evaluate(declaration.Expression, constantEnvironment{s: s})
+	if err, isError := value.(*eval.Error); isError {
+		s.errorWithCode(CodeNotConstant, "constant "+declaration.Token.Literal+" can't be evaluated at compile time: "+err.Message, err.Token)
+		return
+	}
+
+	declaredType.Value = value
+}
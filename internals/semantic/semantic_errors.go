@@ -2,6 +2,7 @@ package semantic
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/gabivlj/candice/internals/ast"
@@ -12,10 +13,73 @@ import (
 	"github.com/gabivlj/candice/pkg/logger"
 )
 
+// ErrorCode identifies the kind of a SemanticError, it doesn't change between versions so tools can filter by it
+type ErrorCode string
+
+const (
+	CodeSemantic             ErrorCode = "semantic"
+	CodeTypeMismatch         ErrorCode = "type-mismatch"
+	CodePointerDepth         ErrorCode = "pointer-depth"
+	CodeInvalidOperation     ErrorCode = "invalid-operation"
+	CodeNotConstant          ErrorCode = "not-constant"
+	CodeUndefined            ErrorCode = "undefined"
+	CodeBuiltin              ErrorCode = "builtin"
+	CodeImport               ErrorCode = "import"
+	CodeMissingReturn        ErrorCode = "missing-return"
+	CodeRecursiveType        ErrorCode = "recursive-type"
+	CodeValueCount           ErrorCode = "value-count"
+	CodeMisplacedStatement   ErrorCode = "misplaced-statement"
+	CodeConstantMutation     ErrorCode = "constant-mutation"
+	CodeUnionCoercion        ErrorCode = "union-coercion"
+	CodeMultipleStringAdding ErrorCode = "multiple-string-adding"
+)
+
+// SemanticError is an error or a warning found while analyzing a module
 type SemanticError struct {
-	token   token.Token
-	code    ast.Node
-	message string
+	Span token.Span
+	// Code is the kind of the error, CodeSemantic when it's not specified
+	Code ErrorCode
+	// Message describes the error without the source that caused it
+	Message string
+	// Hints suggest how to fix the error
+	Hints []string
+	// text is the message with the source and the hints, as it's printed on the terminal
+	text string
+}
+
+func (e *SemanticError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Span, e.text)
+}
+
+// Diagnostic returns the error with its location so tools can read it
+func (e *SemanticError) Diagnostic() logger.Diagnostic {
+	code := e.Code
+	if code == "" {
+		code = CodeSemantic
+	}
+
+	return logger.Diagnostic{
+		Severity:  logger.SeverityError,
+		Code:      string(code),
+		Message:   e.Message,
+		File:      e.Span.File.Name(),
+		Line:      e.Span.Line,
+		Column:    e.Span.Position,
+		EndLine:   e.Span.EndLine,
+		EndColumn: e.Span.EndPosition,
+		Hints:     e.Hints,
+	}
+}
+
+// splitHints separates the message from the hints that follow it as "Hint: ..."
+func splitHints(msg string) (string, []string) {
+	parts := strings.Split(msg, "\nHint: ")
+	hints := make([]string, 0, len(parts)-1)
+	for _, hint := range parts[1:] {
+		hints = append(hints, strings.TrimSpace(hint))
+	}
+
+	return strings.TrimSpace(parts[0]), hints
 }
 
 func (s *Semantic) checkWarningForMultipleStringAdding(binaryOperation *ast.BinaryOperation) {
@@ -23,17 +87,21 @@ func (s *Semantic) checkWarningForMultipleStringAdding(binaryOperation *ast.Bina
 	r, containsMoreToRight := binaryOperation.Right.(*ast.BinaryOperation)
 
 	if containsMoreToLeft || containsMoreToRight && (l != nil && l.Operation == ops.Add || r != nil && r.Operation == ops.Add) {
-		s := binaryOperation.String()
-		logger.Warning(fmt.Sprintf("You are adding more than 2 strings together, this can lead to a memory leak in your application because you can lose references to strings,"+
-			" consider separating strings in different declarations.\n%s\n%s  Happening here", s, strings.Repeat("^", len(s))))
+		message := "you are adding more than 2 strings together, this can lead to a memory leak in your application because you can lose references to strings"
+		hint := "consider separating strings in different declarations"
+		s.Warnings = append(s.Warnings, &SemanticError{
+			Span:    binaryOperation.Span(),
+			Code:    CodeMultipleStringAdding,
+			Message: message,
+			Hints:   []string{hint},
+			text:    fmt.Sprintf("%s\n%s\nHint: %s", message, s.formatExpression(binaryOperation), hint),
+		})
 	}
 }
 
 func (s *Semantic) cantOperateThisOperationError(binaryOperation *ast.BinaryOperation, leftType ctypes.Type, rightType ctypes.Type) {
-	line := fmt.Sprintf("%s", binaryOperation.String())
-	markedLine := fmt.Sprintf("\t%s\n\t%s", line, strings.Repeat("^", len(line)))
-	message := fmt.Sprintf("can't use a '%s' between a '%s' and a '%s'\n>>%s", binaryOperation.Operation, leftType, rightType, markedLine)
-	s.error(message, binaryOperation.Token)
+	message := fmt.Sprintf("can't use a '%s' between a '%s' and a '%s'", binaryOperation.Operation, leftType, rightType)
+	s.errorOnSpan(CodeInvalidOperation, message, "\n"+s.formatExpression(binaryOperation), spanOf(binaryOperation))
 }
 
 func (s *Semantic) checkDereferenceOnArithmeticErrors(left, right ctypes.Type, binary *ast.BinaryOperation, prioritiseDereferenceFix bool) {
@@ -57,37 +125,39 @@ func (s *Semantic) checkDereferenceOnArithmeticErrors(left, right ctypes.Type, b
 		return
 	}
 
-	message := fmt.Sprintf("%s differ on their pointer depth, (%s ≠ %s)\n", binary, left, right)
+	message := fmt.Sprintf("%s differ on their pointer depth, (%s ≠ %s)", binary, left, right)
 
 	diff := depthRight - depthLeft
 	if diff < 0 {
 		diff = -diff
 	}
 
-	message += s.getCurrentStatementLineFormatted()
+	var hint string
 	if depthLeft > depthRight {
 		if prioritiseDereferenceFix {
 			newLeft := s.wrapInOperators(leftExpr, diff, ops.Multiply)
 			binary.Left = newLeft
-			message += fmt.Sprintf("\nHint: consider dereferencing '%s' as '%s'\n", leftExpr, newLeft)
+			hint = fmt.Sprintf("consider dereferencing '%s' as '%s'", leftExpr, newLeft)
 		} else {
 			newRight := s.wrapInOperators(rightExpr, diff, ops.Reference)
 			binary.Right = newRight
-			message += fmt.Sprintf("\nHint: consider referencing '%s' as '%s'\n", rightExpr, newRight)
+			hint = fmt.Sprintf("consider referencing '%s' as '%s'", rightExpr, newRight)
 		}
 	} else {
 		if !prioritiseDereferenceFix {
 			newLeft := s.wrapInOperators(leftExpr, diff, ops.Reference)
 			binary.Left = newLeft
-			message += fmt.Sprintf("\nHint: consider referencing '%s' as '%s'\n", leftExpr, newLeft)
+			hint = fmt.Sprintf("consider referencing '%s' as '%s'", leftExpr, newLeft)
 		} else {
 			newRight := s.wrapInOperators(rightExpr, diff, ops.Multiply)
 			binary.Right = newRight
-			message += fmt.Sprintf("\nHint: consider dereferencing '%s' as '%s'\n", rightExpr, newRight)
+			hint = fmt.Sprintf("consider dereferencing '%s' as '%s'", rightExpr, newRight)
 		}
 	}
-	message += s.getCurrentStatementLineFormatted()
-	s.error(message, leftExpr.GetToken())
+
+	// the statement has the suggested expression now
+	text := fmt.Sprintf("%s\n%s\nHint: %s\n%s", message, s.formatExpression(binary), hint, s.getCurrentStatementRewritten())
+	s.addError(&SemanticError{Span: spanOf(binary), Code: CodePointerDepth, Message: message, Hints: []string{hint}, text: text})
 }
 
 func (s *Semantic) checkDereferenceErrors(line string, left, right ctypes.Type, leftExpr ast.Expression) {
@@ -101,32 +171,60 @@ func (s *Semantic) checkDereferenceErrors(line string, left, right ctypes.Type,
 		return
 	}
 
-	message := fmt.Sprintf("%s differs on the expected type pointer depth, (%s ≠ %s)\n", leftExpr, left, right)
+	message := fmt.Sprintf("%s differs on the expected type pointer depth, (%s ≠ %s)", leftExpr, left, right)
 
 	diff := depthRight - depthLeft
 	if diff < 0 {
 		diff = -diff
 	}
 
-	message += s.getCurrentStatementLineFormatted()
+	var hint string
 	if depthLeft > depthRight {
 		newLeft := s.wrapInOperators(leftExpr, diff, ops.Multiply)
-		message += fmt.Sprintf("\nHint: consider dereferencing '%s' as '%s'\n", leftExpr, newLeft)
+		hint = fmt.Sprintf("consider dereferencing '%s' as '%s'", leftExpr, newLeft)
 	} else {
 		newRight := s.wrapInOperators(leftExpr, diff, ops.Reference)
-		message += fmt.Sprintf("\nHint: consider referencing '%s' as '%s'\n", leftExpr, newRight)
+		hint = fmt.Sprintf("consider referencing '%s' as '%s'", leftExpr, newRight)
 	}
 
-	s.error(message, leftExpr.GetToken())
+	text := fmt.Sprintf("%s\n%s\nHint: %s\n", message, s.formatExpression(leftExpr), hint)
+	s.addError(&SemanticError{Span: spanOf(leftExpr), Code: CodePointerDepth, Message: message, Hints: []string{hint}, text: text})
 }
 
-func (semantic *Semantic) formatExpression(expr ast.Expression) string {
+func (semantic *Semantic) formatExpression(expr ast.Node) string {
+	if source, ok := semantic.formatSource(expr.Span()); ok {
+		return source
+	}
+
 	s := expr.String()
 	return semantic.formatLine(s)
 }
 
+// getCurrentStatementRewritten formats the statement that is being analyzed as it's in the tree,
+// the hints rewrite its expressions to show how to fix them so its source isn't used
+func (semantic *Semantic) getCurrentStatementRewritten() string {
+	if semantic.currentStatementBeingAnalyzed == nil {
+		return ""
+	}
+
+	return semantic.formatLine(semantic.currentStatementBeingAnalyzed.String())
+}
+
+// spanOf returns the span of the node, or the span of its token when the compiler created the node
+func spanOf(node ast.Node) token.Span {
+	if span := node.Span(); !span.IsZero() {
+		return span
+	}
+
+	return node.GetToken().Span()
+}
+
 func (semantic *Semantic) getCurrentStatementLineFormatted() string {
 	if semantic.currentStatementBeingAnalyzed != nil {
+		if source, ok := semantic.formatSource(semantic.currentStatementBeingAnalyzed.Span()); ok {
+			return source
+		}
+
 		s := semantic.currentStatementBeingAnalyzed.String()
 		return semantic.formatLine(s)
 	}
@@ -134,6 +232,21 @@ func (semantic *Semantic) getCurrentStatementLineFormatted() string {
 	return ""
 }
 
+// formatSource underlines the span in the source of its file, it returns false if the source isn't known
+func (semantic *Semantic) formatSource(span token.Span) (string, bool) {
+	source := span.File.Source()
+	if source == "" || span.IsZero() {
+		return "", false
+	}
+
+	underline := strings.SplitN(span.Underline(source), "\n", 2)
+	if len(underline) != 2 {
+		return "", false
+	}
+
+	return fmt.Sprintf("\n\t>> %s\n\t   %s\n", underline[0], underline[1]), true
+}
+
 func (semantic *Semantic) formatLine(s string) string {
 	elements := strings.Split(format.StringWithTabs(s, 1), "\n")
 	currentStatementLen := len(elements[0])
@@ -141,24 +254,31 @@ func (semantic *Semantic) formatLine(s string) string {
 }
 
 func (s *Semantic) typeMismatchError(node string, wrongPart ast.Expression, tok token.Token, expected, got ctypes.Type) {
-	var message string
-
 	s.checkDereferenceErrors(node, got, expected, wrongPart)
 
-	if len(s.Errors) == 0 {
-		message = fmt.Sprintf("\n\n%s \n%s mismatched types, expected %s, got %s\n", node, strings.Repeat("^", len(node)), expected.String(), got.String())
-	} else {
-		message = fmt.Sprintf("can't recover from the errors\n")
+	if len(s.Errors) > 0 {
+		s.addError(&SemanticError{
+			Span:    tok.Span(),
+			Code:    CodeTypeMismatch,
+			Message: "can't recover from the errors",
+			Hints:   []string{"check compiler errors above."},
+			text:    "can't recover from the errors\nHint: check compiler errors above.",
+		})
+
+		return
 	}
 
-	if wrongPart != nil && len(s.Errors) == 0 {
-		left := wrongPart.String()
-		message += fmt.Sprintf("Hint: maybe are you missing a cast here?\n%s\n%s\n", left, strings.Repeat("^", len(left)))
-	} else if len(s.Errors) > 0 {
-		message += fmt.Sprintf("Hint: check compiler errors above.")
+	message := fmt.Sprintf("mismatched types, expected %s, got %s", expected.String(), got.String())
+	text := fmt.Sprintf("\n\n%s \n%s %s\n", node, strings.Repeat("^", len(node)), message)
+	span := tok.Span()
+	var hints []string
+	if wrongPart != nil {
+		span = spanOf(wrongPart)
+		hints = append(hints, "maybe are you missing a cast here?")
+		text += fmt.Sprintf("Hint: maybe are you missing a cast here?\n%s", s.formatExpression(wrongPart))
 	}
 
-	s.error(message, tok)
+	s.addError(&SemanticError{Span: span, Code: CodeTypeMismatch, Message: message, Hints: hints, text: text})
 }
 
 // Type mismatch for an arithmetic operation like '+' excepting '<<' and '>>',
@@ -177,7 +297,7 @@ func (s *Semantic) typeMismatchBlameArithmeticExpressionError(node string, binar
 // Type mismatch for every binary operation
 func (s *Semantic) typeMismatchBlameBinaryExpressionError(node string, binary *ast.BinaryOperation, tok token.Token, expected ctypes.Type, got ctypes.Type, blameRight bool) {
 	s.checkDereferenceOnArithmeticErrors(binary.Left.GetType(), binary.Right.GetType(), binary, true)
-	wrongLine := s.getCurrentStatementLineFormatted()
+	wrongLine := s.formatExpression(binary)
 	// Decide which side of the operation do you want to cast
 	if blameRight {
 		binary.Right = &ast.BuiltinCall{
@@ -192,12 +312,15 @@ func (s *Semantic) typeMismatchBlameBinaryExpressionError(node string, binary *a
 			Parameters:     []ast.Expression{binary.Left},
 		}
 	}
-	message := fmt.Sprintf("\n%s\n\n%s \n%s mismatched types, expected %s, got %s\n", wrongLine, node, strings.Repeat("^", len(node)), expected.String(), got.String())
+	message := fmt.Sprintf("mismatched types, expected %s, got %s", expected.String(), got.String())
+	text := fmt.Sprintf("\n%s\n\n%s \n%s %s\n", wrongLine, node, strings.Repeat("^", len(node)), message)
+	var hints []string
 	if ctypes.IsNumeric(expected) && ctypes.IsNumeric(got) {
-		message += fmt.Sprintf("Try doing:\n- [%d:%d] %s\n", binary.GetToken().Line, binary.GetToken().Position, binary.String())
+		hints = append(hints, "try doing "+binary.String())
+		text += fmt.Sprintf("Try doing:\n- [%d:%d] %s\n", spanOf(binary).Line, spanOf(binary).Position, binary.String())
 	}
 
-	s.error(message, tok)
+	s.addError(&SemanticError{Span: spanOf(binary), Code: CodeTypeMismatch, Message: message, Hints: hints, text: text})
 }
 
 func (s *Semantic) throwInvalidOperationForConstant(message string, node ast.Node) {
@@ -205,5 +328,18 @@ func (s *Semantic) throwInvalidOperationForConstant(message string, node ast.Nod
 		return
 	}
 
-	s.error(fmt.Sprintf("%sthis is an invalid operation for a constant expression\n\t%s", message, s.formatLine(node.String())), node.GetToken())
+	s.addError(&SemanticError{
+		Span:    spanOf(node),
+		Code:    CodeNotConstant,
+		Message: strings.TrimSpace(message) + ", this is an invalid operation for a constant expression",
+		text:    fmt.Sprintf("%sthis is an invalid operation for a constant expression\n\t%s", message, s.formatExpression(node)),
+	})
+}
+
+// exitInternalError is for states that the analyzer doesn't know how to handle, it writes the diagnostics
+// that haven't been written yet because os.Exit doesn't run the deferred calls that would write them
+func (s *Semantic) exitInternalError(message string) {
+	logger.Error("Internal error", "Unknown internal error has happened, check below for more details", "\n"+message)
+	logger.Flush()
+	os.Exit(1)
 }
@@ -0,0 +1,128 @@
+package semantic
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// loader is shared between every module of a build. It deduplicates modules that are imported
+// more than once, keeps the import graph to detect cycles and limits how many modules
+// are analyzed at the same time.
+type loader struct {
+	mu sync.Mutex
+
+	// modules by the hash of their path plus their generic types
+	modules map[string]*moduleFuture
+
+	// import graph, key imports every module of the value
+	imports map[string][]string
+
+	// names of every module for error messages
+	names map[string]string
+
+	workers chan struct{}
+
+	// directory of every dependency by name
+	dependencies map[string]string
+	searchPaths  []string
+	stdDirectory string
+
+	// key of the module that started the build
+	root string
+}
+
+// moduleFuture is a module that is being analyzed or has been analyzed.
+// done is closed when the analysis finishes.
+type moduleFuture struct {
+	done     chan struct{}
+	semantic *Semantic
+
+	// when the module can't be imported, failure explains why and errors
+	// contains the errors of the module itself.
+	failure string
+	errors  []error
+}
+
+func newLoader() *loader {
+	return &loader{
+		modules: map[string]*moduleFuture{},
+		imports: map[string][]string{},
+		names:   map[string]string{},
+		workers: make(chan struct{}, runtime.GOMAXPROCS(0)),
+	}
+}
+
+func (l *loader) acquire() {
+	l.workers <- struct{}{}
+}
+
+func (l *loader) release() {
+	<-l.workers
+}
+
+// request registers that the module from imports the module to, returning the module future
+// and if the caller is the one that should analyze it. If the import introduces an import cycle
+// it returns the chain of modules that form the cycle.
+func (l *loader) request(from, to, name string) (future *moduleFuture, isNew bool, cycle []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.names[to] = name
+	if chain := l.findPath(to, from, map[string]bool{}); chain != nil {
+		cycle = make([]string, 0, len(chain)+1)
+		cycle = append(cycle, l.names[from])
+		for _, module := range chain {
+			cycle = append(cycle, l.names[module])
+		}
+
+		return nil, false, cycle
+	}
+
+	l.imports[from] = append(l.imports[from], to)
+	if future, ok := l.modules[to]; ok {
+		return future, false, nil
+	}
+
+	future = &moduleFuture{done: make(chan struct{})}
+	l.modules[to] = future
+	return future, true, nil
+}
+
+// findPath returns the modules that go from 'from' to 'to' on the import graph, both included.
+func (l *loader) findPath(from, to string, visited map[string]bool) []string {
+	if from == to {
+		return []string{to}
+	}
+
+	if visited[from] {
+		return nil
+	}
+
+	visited[from] = true
+	for _, next := range l.imports[from] {
+		if chain := l.findPath(next, to, visited); chain != nil {
+			return append([]string{from}, chain...)
+		}
+	}
+
+	return nil
+}
+
+// chainTo returns the names of the modules that lead from the root module to the module with that key.
+func (l *loader) chainTo(key string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	chain := l.findPath(l.root, key, map[string]bool{})
+	names := make([]string, 0, len(chain))
+	for _, module := range chain {
+		names = append(names, l.names[module])
+	}
+
+	return names
+}
+
+func formatImportCycle(cycle []string) string {
+	return "import cycle detected: " + strings.Join(cycle, " -> ")
+}
@@ -0,0 +1,149 @@
+package semantic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gabivlj/candice/internals/ctypes"
+)
+
+// ModuleID returns a stable identifier for a module located on filePath with the
+// passed source code and instantiated with the generic types described by typesKey.
+//
+// The identifier is used as the parser ID of the module, so symbols generated
+// for the module are the same across builds as long as the module doesn't change.
+func ModuleID(filePath string, source []byte, typesKey string) string {
+	hash := sha256.New()
+	hash.Write([]byte(filePath))
+	hash.Write([]byte{0})
+	hash.Write([]byte(typesKey))
+	hash.Write([]byte{0})
+	hash.Write(source)
+	return hex.EncodeToString(hash.Sum(nil))[:10]
+}
+
+// ComputeChecksum calculates the checksum of the analyzed module, it takes into account the source
+// code of the module, the generic types that were used to instantiate it and the checksums of every module
+// that it imports. This means that if an imported module changes, the checksum of this one changes too.
+// It needs to be called after analyzing the module.
+func (s *Semantic) ComputeChecksum(source []byte, typesKey string) string {
+	hash := sha256.New()
+	hash.Write([]byte(s.FilePath))
+	hash.Write([]byte{0})
+	hash.Write([]byte(typesKey))
+	hash.Write([]byte{0})
+	hash.Write(source)
+	checksums := make([]string, 0, len(s.modules))
+	for _, module := range s.Imports() {
+		checksums = append(checksums, module.Checksum)
+	}
+
+	sort.Strings(checksums)
+	for _, checksum := range checksums {
+		hash.Write([]byte{0})
+		hash.Write([]byte(checksum))
+	}
+
+	s.Checksum = hex.EncodeToString(hash.Sum(nil))
+	return s.Checksum
+}
+
+// typesLayout describes the generic types that instantiate a module for its checksum. The name of
+// a struct or a union isn't enough, their fields can change without changing the name and the module
+// would need to be compiled again, so their fields are described too.
+func typesLayout(types []ctypes.Type) string {
+	layout := strings.Builder{}
+	for _, t := range types {
+		layout.WriteByte(',')
+		writeLayout(&layout, t, map[ctypes.Type]struct{}{})
+	}
+
+	return layout.String()
+}
+
+func writeLayout(layout *strings.Builder, t ctypes.Type, seen map[ctypes.Type]struct{}) {
+	var names []string
+	var fields []ctypes.Type
+	switch t := t.(type) {
+	case *ctypes.Pointer:
+		layout.WriteByte('*')
+		writeLayout(layout, t.Inner, seen)
+		return
+	case *ctypes.Array:
+		layout.WriteString(fmt.Sprintf("[%d]", t.Length))
+		writeLayout(layout, t.Inner, seen)
+		return
+	case *ctypes.Struct:
+		names, fields = t.Names, t.Fields
+	case *ctypes.Union:
+		names, fields = t.Names, t.Fields
+	default:
+		layout.WriteString(t.String())
+		return
+	}
+
+	layout.WriteString(t.String())
+	// types that point to themselves are only described once
+	if _, ok := seen[t]; ok {
+		return
+	}
+
+	seen[t] = struct{}{}
+	layout.WriteByte('{')
+	for i, field := range fields {
+		layout.WriteString(names[i])
+		layout.WriteByte(' ')
+		writeLayout(layout, field, seen)
+		layout.WriteByte(';')
+	}
+
+	layout.WriteByte('}')
+}
+
+// Imports returns the modules that are directly imported by this module, without duplicates.
+func (s *Semantic) Imports() []*Semantic {
+	seen := map[*Semantic]struct{}{}
+	modules := make([]*Semantic, 0, len(s.modules))
+	for _, module := range s.modules {
+		if _, ok := seen[module]; ok {
+			continue
+		}
+
+		seen[module] = struct{}{}
+		modules = append(modules, module)
+	}
+
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Root.ID < modules[j].Root.ID
+	})
+
+	return modules
+}
+
+// Files returns the path of every file that this module tried to import, directly or
+// through other modules, including the ones that couldn't be analyzed.
+// It needs to be called after analyzing the module.
+func (s *Semantic) Files() []string {
+	if s.loader == nil {
+		return nil
+	}
+
+	s.loader.mu.Lock()
+	defer s.loader.mu.Unlock()
+	seen := map[string]struct{}{}
+	files := make([]string, 0, len(s.loader.names))
+	for key, name := range s.loader.names {
+		if _, ok := seen[name]; ok || key == s.key {
+			continue
+		}
+
+		seen[name] = struct{}{}
+		files = append(files, name)
+	}
+
+	sort.Strings(files)
+	return files
+}
@@ -0,0 +1,141 @@
+package semantic
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gabivlj/candice/internals/ast"
+)
+
+// importCandidate is a file where an import might be, reason explains why we look there.
+type importCandidate struct {
+	path   string
+	reason string
+}
+
+// resolveImport returns the path of the file that the import statement refers to. If the file can't
+// be found, it reports an error with every place where it looked for it and the chain of imports
+// that led to this module.
+func (s *Semantic) resolveImport(importStatement *ast.ImportStatement) (string, bool) {
+	importPath := importStatement.Path.Value
+	candidates := s.importCandidates(importPath)
+	for _, candidate := range candidates {
+		if candidate.path == "" {
+			continue
+		}
+
+		if info, err := os.Stat(candidate.path); err == nil && !info.IsDir() {
+			return candidate.path, true
+		}
+	}
+
+	message := strings.Builder{}
+	message.WriteString(fmt.Sprintf("can't find the file imported on path %q, tried:", importPath))
+	for _, candidate := range candidates {
+		if candidate.path == "" {
+			message.WriteString("\n\t- " + candidate.reason)
+			continue
+		}
+
+		message.WriteString(fmt.Sprintf("\n\t- %s (%s)", candidate.path, candidate.reason))
+	}
+
+	if chain := s.loader.chainTo(s.key); len(chain) > 1 {
+		message.WriteString("\nimported through: " + strings.Join(chain, " -> "))
+	}
+
+	s.errorWithCode(CodeImport, message.String(), importStatement.Token)
+	return "", false
+}
+
+// importCandidates returns the files where importPath is looked for, in order:
+//   - If the first element of the path is the name of a dependency, only inside of the dependency.
+//   - Relative to the directory of the importing file.
+//   - Relative to each search path, like the project root.
+//   - For paths that start with 'std/', inside of the standard library.
+//
+// Paths like 'std:array' are only looked for in the standard library.
+func (s *Semantic) importCandidates(importPath string) []importCandidate {
+	if path.IsAbs(importPath) {
+		return []importCandidate{{path: importPath, reason: "absolute path"}}
+	}
+
+	// "std:array" refers to std/array.cd of the standard library, it's never looked for anywhere else
+	if strings.HasPrefix(importPath, "std:") {
+		module := strings.TrimPrefix(importPath, "std:")
+		if path.Ext(module) == "" {
+			module += ".cd"
+		}
+
+		return []importCandidate{s.stdCandidate(module)}
+	}
+
+	dependency, rest, _ := strings.Cut(importPath, "/")
+	if directory, ok := s.loader.dependencies[dependency]; ok && rest != "" {
+		return []importCandidate{{path: path.Join(directory, rest), reason: "dependency " + dependency}}
+	}
+
+	candidates := []importCandidate{}
+	if filePath := s.absoluteFilePath(); filePath != "" {
+		candidates = append(candidates, importCandidate{
+			path:   path.Join(path.Dir(filePath), importPath),
+			reason: "relative to " + filePath,
+		})
+	} else {
+		currentPath := s.ContextDirectoryPath
+		if !path.IsAbs(currentPath) {
+			currentPath = path.Join(workingDirectory(), currentPath)
+		}
+
+		candidates = append(candidates, importCandidate{
+			path:   path.Join(currentPath, importPath),
+			reason: "relative to " + currentPath,
+		})
+	}
+
+	for _, searchPath := range s.loader.searchPaths {
+		if !path.IsAbs(searchPath) {
+			searchPath = path.Join(workingDirectory(), searchPath)
+		}
+
+		candidates = append(candidates, importCandidate{
+			path:   path.Join(searchPath, importPath),
+			reason: "search path " + searchPath,
+		})
+	}
+
+	if strings.HasPrefix(importPath, "std/") {
+		candidates = append(candidates, s.stdCandidate(strings.TrimPrefix(importPath, "std/")))
+	}
+
+	return candidates
+}
+
+func (s *Semantic) stdCandidate(module string) importCandidate {
+	// An empty path means that the candidate can't be checked
+	if s.loader.stdDirectory == "" {
+		return importCandidate{reason: "standard library, but its directory couldn't be found"}
+	}
+
+	return importCandidate{path: path.Join(s.loader.stdDirectory, module), reason: "standard library"}
+}
+
+// absoluteFilePath returns the absolute path of the file of this module, or empty if it's unknown.
+func (s *Semantic) absoluteFilePath() string {
+	if s.FilePath == "" || path.IsAbs(s.FilePath) {
+		return s.FilePath
+	}
+
+	return path.Join(workingDirectory(), s.FilePath)
+}
+
+func workingDirectory() string {
+	directory, err := os.Getwd()
+	if err != nil {
+		return "/"
+	}
+
+	return directory
+}
@@ -33,7 +33,10 @@ func (s *Semantic) predefineFunctions(statements []ast.Statement) {
 				s.enterFrame()
 				s.replaceAnonymousFunctionParameterTypes(t.FunctionType)
 				s.leaveFrame()
-				s.variables.Add(t.FunctionType.Name, s.newType(t.FunctionType))
+				s.functions[t.FunctionType.Name] = t
+				functionType := s.newType(t.FunctionType)
+				functionType.IsConstant = t.FunctionType.Comptime
+				s.variables.Add(t.FunctionType.Name, functionType)
 			}
 
 		case *ast.MacroBlock:
@@ -47,6 +50,14 @@ func (s *Semantic) predefineFunctions(statements []ast.Statement) {
 func (s *Semantic) fillTypes(statements []ast.Statement) {
 	for _, statement := range statements {
 		s.currentStatementBeingAnalyzed = statement
+		// Consecutive imports are analyzed concurrently, the rest of
+		// statements might reference them so we need to wait for them.
+		if _, isImport := statement.(*ast.ImportStatement); !isImport {
+			if _, isMacro := statement.(*ast.MacroBlock); !isMacro {
+				s.waitImports()
+			}
+		}
+
 		switch t := statement.(type) {
 		case *ast.TypeDefinition:
 			{
@@ -1,6 +1,10 @@
 package semantic
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gabivlj/candice/internals/lexer"
@@ -331,7 +335,6 @@ func TestSemantic_Analyze(t *testing.T) {
 			}`,
 			false,
 		},
-
 		// This still doesn't work...
 		// {
 		// 	`struct C { p Point } struct Point { p C }`,
@@ -352,3 +355,304 @@ func TestSemantic_Analyze(t *testing.T) {
 		}
 	}
 }
+
+type programTest struct {
+	program    string
+	shouldBeOk bool
+}
+
+// analyzePrograms analyzes every program on its own and reports the ones that
+// don't have the expected result.
+func analyzePrograms(t *testing.T, tests []programTest) {
+	t.Helper()
+	for _, test := range tests {
+		p := parser.New(lexer.New(test.program))
+		program := p.Parse()
+		if len(p.Errors) != 0 {
+			t.Error(test.program, p.Errors)
+			continue
+		}
+
+		semantic := New()
+		semantic.Analyze(program)
+		if test.shouldBeOk && len(semantic.Errors) != 0 {
+			t.Error(test.program, semantic.Errors)
+		} else if !test.shouldBeOk && len(semantic.Errors) == 0 {
+			t.Error(test.program, "shouldn't be ok but we got 0 Errors...")
+		}
+	}
+}
+
+func TestSemantic_MainArguments(t *testing.T) {
+	analyzePrograms(t, []programTest{
+		{
+			`func main(argc i32, argv **i8) i32 { return argc; }`,
+			true,
+		},
+		{
+			`func main(argc i32) {}`,
+			false,
+		},
+		{
+			`func main() i64 { return 0 as i64; }`,
+			false,
+		},
+	})
+}
+
+func TestSemantic_Atomics(t *testing.T) {
+	analyzePrograms(t, []programTest{
+		{
+			`func main() {
+					x := 0 as i64; p := 0 as *i8;
+					old := @atomic_add(&x, 1 as i64, relaxed) + @atomic_load(&x, acquire);
+					@atomic_store(&x, old, release);
+					swapped : bool = @atomic_cas(&p, p, "hi", seq_cst);
+					@fence(acq_rel);
+				}`,
+			true,
+		},
+		{
+			`func main() { x := 1.5; @atomic_add(&x, 1.5, relaxed); }`,
+			false,
+		},
+		{
+			`func main() { p := 0 as *i8; @atomic_add(&p, p, relaxed); }`,
+			false,
+		},
+		{
+			`func main() { x := 1; @atomic_load(&x, release); }`,
+			false,
+		},
+		{
+			`func main() { x := 1; @atomic_cas(&x, 1 as i64, 2, seq_cst); }`,
+			false,
+		},
+	})
+}
+
+func TestSemantic_Vectors(t *testing.T) {
+	analyzePrograms(t, []programTest{
+		{
+			`func main() {
+					a := [4]f32{1.0, 2.0, 3.0, 4.0} as vec4 f32;
+					b := a * @splat(vec4 f32, 2.0) + a;
+					mask : vec4 bool = a < b;
+					any : bool = @reduce(mask, or);
+					sum : f32 = @reduce(b, add) + @extract(@shuffle(a, b, 0, 4), 1);
+					ints := @insert(b as vec4 i32, 0, 3) ^ @splat(vec4 i32, 1);
+					arr : [4]i32 = ints as [4]i32;
+				}`,
+			true,
+		},
+		{
+			`func main() { a := @splat(vec4 f32, 1.0) + @splat(vec4 i32, 1); }`,
+			false,
+		},
+		{
+			`func main() { a := @splat(vec4 f32, 1.0) ^ @splat(vec4 f32, 1.0); }`,
+			false,
+		},
+		{
+			`func main() { a := @reduce(@splat(vec4 f32, 1.0), xor); }`,
+			false,
+		},
+		{
+			`func main() { a := [3]f32{1.0, 2.0, 3.0} as vec4 f32; }`,
+			false,
+		},
+	})
+}
+
+func TestSemantic_Constants(t *testing.T) {
+	analyzePrograms(t, []programTest{
+		{
+			`type Byte = u8
+				const SIZE := 4 as Byte * 2 as Byte;
+				const BYTES := @sizeof([4]i64) + SIZE as i32;
+				func main() { const HALF := BYTES as f32 / 2.0; @print(HALF); }`,
+			true,
+		},
+		{
+			`const A := 1 / 0;`,
+			false,
+		},
+		{
+			`const A := 300.5 as u8;`,
+			false,
+		},
+		{
+			`func main() { x := 3; const A := x + 1; }`,
+			false,
+		},
+	})
+}
+
+func TestSemantic_Comptime(t *testing.T) {
+	analyzePrograms(t, []programTest{
+		{
+			`comptime func table() [4]i32 {
+					values := [4]i32{};
+					for i := 0; i < 4; ++i { values[i] = i * i; }
+					return values;
+				}
+				const TABLE := table();
+				func main() { @print(TABLE[3]); }`,
+			true,
+		},
+		{
+			`func square(n i32) i32 { return n * n; } const A := square(2);`,
+			false,
+		},
+		{
+			`extern func rand() i32; comptime func random() i32 { return rand(); } const A := random();`,
+			false,
+		},
+	})
+}
+
+func TestSemantic_Imports(t *testing.T) {
+	tests := []struct {
+		files      map[string]string
+		shouldBeOk bool
+		errorPart  string
+	}{
+		{
+			// imports are relative to the importing file
+			files: map[string]string{
+				"main.cd":      `import a, "lib/a.cd" func main() { a.a(); }`,
+				"lib/a.cd":     `import b, "sub/b.cd" func a() i32 { return b.b(); }`,
+				"lib/sub/b.cd": `func b() i32 { return 1; }`,
+			},
+			shouldBeOk: true,
+		},
+		{
+			files: map[string]string{
+				"main.cd":  `import a, "lib/a.cd" func main() { a.a(); }`,
+				"lib/a.cd": `import b, "b.cd" func a() i32 { return b.b(); }`,
+				"b.cd":     `func b() i32 { return 1; }`,
+			},
+			shouldBeOk: false,
+			errorPart:  "lib/b.cd",
+		},
+		{
+			files: map[string]string{
+				"main.cd": `import a, "a.cd" func main() { a.a(); }`,
+				"a.cd":    `import b, "b.cd" func a() {}`,
+				"b.cd":    `import a, "a.cd" func b() {}`,
+			},
+			shouldBeOk: false,
+			errorPart:  "import cycle detected",
+		},
+		{
+			// standard library imports
+			files: map[string]string{
+				"main.cd":         `import a, "std:array" import b, "std/array.cd" func main() { a.new(); b.new(); }`,
+				"stdlib/array.cd": `func new() i32 { return 1; }`,
+			},
+			shouldBeOk: true,
+		},
+		{
+			// multiple return values with types of a module that the caller doesn't import
+			files: map[string]string{
+				"main.cd": `import a, "a.cd" func main() { value, ok := a.a(); value.x = 2; }`,
+				"a.cd":    `import b, "b.cd" func a() (b.B, i32) { return @b.B{ x: 1 }, 1; }`,
+				"b.cd":    `struct B { x i32 }`,
+			},
+			shouldBeOk: true,
+		},
+	}
+
+	for _, test := range tests {
+		directory := t.TempDir()
+		for name, content := range test.files {
+			filePath := filepath.Join(directory, name)
+			a.Assert(os.MkdirAll(filepath.Dir(filePath), 0o755) == nil)
+			a.Assert(os.WriteFile(filePath, []byte(content), 0o644) == nil)
+		}
+
+		p := parser.New(lexer.New(test.files["main.cd"]))
+		program := p.Parse()
+		a.Assert(len(p.Errors) == 0, p.Errors)
+		semantic := New()
+		semantic.FilePath = filepath.Join(directory, "main.cd")
+		semantic.StdDirectory = filepath.Join(directory, "stdlib")
+		semantic.Analyze(program)
+		if test.shouldBeOk && len(semantic.Errors) != 0 {
+			t.Fatal(test.files, semantic.Errors)
+		} else if !test.shouldBeOk && len(semantic.Errors) == 0 {
+			t.Fatal(test.files, "shouldn't be ok but we got 0 Errors...")
+		}
+
+		if test.errorPart != "" && !strings.Contains(fmt.Sprint(semantic.Errors), test.errorPart) {
+			t.Fatal(test.files, "expected error containing", test.errorPart, "got", semantic.Errors)
+		}
+	}
+}
+
+func TestSemantic_BadStatements(t *testing.T) {
+	p := parser.New(lexer.New(`
+func main() {
+	x := 3 +;
+	y := 4;
+	if y == { }
+	@print(y);
+}
+
+struct Point { x i32 }
+
+func broken( { }`))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 3, p.Errors)
+	semantic := New()
+	semantic.Analyze(program)
+	a.Assert(len(semantic.Errors) == 0, semantic.Errors)
+}
+
+func TestSemantic_Diagnostics(t *testing.T) {
+	p := parser.New(lexer.NewFile("diagnostics.cd", `
+func main() {
+	x := 3;
+	pointer := &x;
+	y := x + pointer;
+}`))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+	semantic := New()
+	semantic.Analyze(program)
+	a.Assert(len(semantic.Errors) > 0)
+
+	err, ok := semantic.Errors[0].(*SemanticError)
+	a.Assert(ok, semantic.Errors[0])
+	diagnostic := err.Diagnostic()
+	a.AssertEqual(diagnostic.Code, string(CodePointerDepth))
+	a.AssertEqual(diagnostic.Message, "(x + pointer) differ on their pointer depth, (i32 ≠ *i32)")
+	a.AssertEqual(diagnostic.File, "diagnostics.cd")
+	// the span covers the whole operation
+	a.Assert(diagnostic.Line == 5 && diagnostic.Column == 7 && diagnostic.EndLine == 5 && diagnostic.EndColumn == 18, diagnostic)
+	a.Assert(len(diagnostic.Hints) == 1, diagnostic.Hints)
+	a.AssertEqual(diagnostic.Hints[0], "consider dereferencing 'pointer' as '*pointer'")
+	a.Assert(strings.Contains(err.Error(), "Hint: consider dereferencing"), err.Error())
+	a.Assert(strings.Contains(err.Error(), "(x + *pointer)"), err.Error())
+}
+
+func TestSemantic_WarningDiagnostics(t *testing.T) {
+	p := parser.New(lexer.NewFile("warnings.cd", `
+func main() {
+	s := "a" + "b" + "c";
+}`))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+	semantic := New()
+	semantic.Analyze(program)
+	a.Assert(len(semantic.Errors) == 0, semantic.Errors)
+	a.Assert(len(semantic.Warnings) == 1, semantic.Warnings)
+
+	warning, ok := semantic.Warnings[0].(*SemanticError)
+	a.Assert(ok, semantic.Warnings[0])
+	diagnostic := warning.Diagnostic()
+	a.AssertEqual(diagnostic.Code, string(CodeMultipleStringAdding))
+	a.AssertEqual(diagnostic.File, "warnings.cd")
+	a.Assert(diagnostic.Line == 3 && diagnostic.Column == 7 && diagnostic.EndColumn == 22, diagnostic)
+	a.Assert(len(diagnostic.Hints) == 1, diagnostic.Hints)
+}
@@ -3,6 +3,7 @@ package semantic
 import (
 	"github.com/gabivlj/candice/internals/ast"
 	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/eval"
 	"github.com/gabivlj/candice/internals/node"
 	"github.com/gabivlj/candice/internals/ops"
 )
@@ -11,6 +12,9 @@ type SemanticType struct {
 	parentFunction *ctypes.Function
 	Type           ctypes.Type
 	IsConstant     bool
+
+	// Value is the value of constants that can be evaluated at compile time
+	Value eval.Value
 }
 
 func (s *Semantic) newType(t ctypes.Type) *SemanticType {
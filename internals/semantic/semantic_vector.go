@@ -0,0 +1,203 @@
+package semantic
+
+import (
+	"fmt"
+
+	"github.com/gabivlj/candice/internals/ast"
+	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/ops"
+)
+
+// analyzeVectorArithmetic analyzes an operation between two vectors of the same type, operations
+// are done element by element and comparisons return a vector of booleans.
+func (s *Semantic) analyzeVectorArithmetic(binaryOperation *ast.BinaryOperation, vector *ctypes.Vector) ctypes.Type {
+	op := binaryOperation.Operation
+	if op == ops.AND || op == ops.OR {
+		s.errorWithCode(CodeInvalidOperation, "vectors don't short circuit, use & or | to operate vectors of booleans", binaryOperation.Token)
+		return ctypes.TODO()
+	}
+
+	if op.IsComparison() {
+		return &ctypes.Vector{Inner: ctypes.I1, Length: vector.Length}
+	}
+
+	isBitwise := op == ops.BinaryAND || op == ops.BinaryOR || op == ops.BinaryXOR
+	if (isBitwise && ctypes.IsFloat(vector.Inner)) || (!isBitwise && vector.Inner == ctypes.I1) {
+		s.cantOperateThisOperationError(binaryOperation, vector, vector)
+		return ctypes.TODO()
+	}
+
+	return vector
+}
+
+// analyzeVectorCast checks casts that involve vectors: arrays and vectors with the same length and
+// element type can be cast to each other, and vectors of numbers can be converted to vectors of
+// other numbers with the same length. Arrays of booleans aren't laid out like vectors of booleans
+// so they can't be cast.
+func (s *Semantic) analyzeVectorCast(castCall *ast.BuiltinCall, from, to ctypes.Type) ctypes.Type {
+	length, inner := vectorOrArrayShape(from)
+	toLength, toInner := vectorOrArrayShape(to)
+	hasBooleans := inner == ctypes.I1 || toInner == ctypes.I1
+	isConversion := ctypes.IsVector(from) && ctypes.IsVector(to) && !hasBooleans
+	isReinterpretation := !hasBooleans && s.areTypesEqual(inner, toInner)
+	if length == 0 || length != toLength || !(isConversion || isReinterpretation || s.areTypesEqual(from, to)) {
+		s.builtinError("can't cast "+from.String()+" to "+to.String(), castCall.Token)
+		return ctypes.TODO()
+	}
+
+	castCall.Type = to
+	return to
+}
+
+func vectorOrArrayShape(t ctypes.Type) (int64, ctypes.Type) {
+	switch t := t.(type) {
+	case *ctypes.Vector:
+		return t.Length, t.Inner
+	case *ctypes.Array:
+		return t.Length, t.Inner
+	}
+
+	return 0, nil
+}
+
+func (s *Semantic) analyzeVector(call *ast.BuiltinCall, parameter ast.Expression) *ctypes.Vector {
+	t := s.UnwrapAnonymous(s.analyzeExpression(parameter))
+	vector, isVector := t.(*ctypes.Vector)
+	if !isVector {
+		s.builtinError("expected a vector on "+call.Name+", got: "+t.String(), call.Token)
+		return nil
+	}
+
+	return vector
+}
+
+// analyzeSplat analyzes @splat(vec4 f32, value), which creates a vector with every element set to value
+func (s *Semantic) analyzeSplat(call *ast.BuiltinCall) ctypes.Type {
+	s.throwInvalidOperationForConstant("you can't create vectors on constants", call)
+	vector, isVector := call.TypeParameters[0].(*ctypes.Vector)
+	if !isVector {
+		s.builtinError("expected a vector type on splat, got: "+call.TypeParameters[0].String(), call.Token)
+		return ctypes.TODO()
+	}
+
+	t := s.analyzeExpression(call.Parameters[0])
+	if !s.areTypesEqual(vector.Inner, t) {
+		s.typeMismatchError(call.String(), call.Parameters[0], call.Token, vector.Inner, t)
+	}
+
+	return vector
+}
+
+// analyzeExtract analyzes @extract(vector, index), which returns the element at index
+func (s *Semantic) analyzeExtract(call *ast.BuiltinCall) ctypes.Type {
+	s.throwInvalidOperationForConstant("you can't use vectors on constants", call)
+	vector := s.analyzeVector(call, call.Parameters[0])
+	s.analyzeVectorIndex(call, call.Parameters[1])
+	if vector == nil {
+		return ctypes.TODO()
+	}
+
+	return vector.Inner
+}
+
+// analyzeInsert analyzes @insert(vector, index, value), which returns a copy of the vector with
+// the element at index set to value
+func (s *Semantic) analyzeInsert(call *ast.BuiltinCall) ctypes.Type {
+	s.throwInvalidOperationForConstant("you can't use vectors on constants", call)
+	vector := s.analyzeVector(call, call.Parameters[0])
+	s.analyzeVectorIndex(call, call.Parameters[1])
+	if vector == nil {
+		return ctypes.TODO()
+	}
+
+	t := s.analyzeExpression(call.Parameters[2])
+	if !s.areTypesEqual(vector.Inner, t) {
+		s.typeMismatchError(call.String(), call.Parameters[2], call.Token, vector.Inner, t)
+	}
+
+	return vector
+}
+
+func (s *Semantic) analyzeVectorIndex(call *ast.BuiltinCall, index ast.Expression) {
+	t := s.UnwrapAnonymous(s.analyzeExpression(index))
+	if !ctypes.IsInteger(t) {
+		s.typeMismatchError(call.String(), index, call.Token, ctypes.I32, t)
+	}
+}
+
+// analyzeShuffle analyzes @shuffle(a, b, 0, 4, 1, 5), which returns a vector with the elements
+// of a and b at the constant indexes, where the elements of b start at the length of a.
+func (s *Semantic) analyzeShuffle(call *ast.BuiltinCall) ctypes.Type {
+	s.throwInvalidOperationForConstant("you can't use vectors on constants", call)
+	if len(call.Parameters) < 3 {
+		s.builtinError("expected two vectors and the indexes of the elements on shuffle", call.Token)
+		return ctypes.TODO()
+	}
+
+	vector := s.analyzeVector(call, call.Parameters[0])
+	second := s.analyzeVector(call, call.Parameters[1])
+	if vector == nil || second == nil {
+		return ctypes.TODO()
+	}
+
+	if !s.areTypesEqual(vector, second) {
+		s.typeMismatchError(call.String(), call.Parameters[1], call.Token, vector, second)
+		return ctypes.TODO()
+	}
+
+	indexes := call.Parameters[2:]
+	if _, isValidLength := ctypes.VectorLength(fmt.Sprintf("vec%d", len(indexes))); !isValidLength {
+		s.builtinError(fmt.Sprintf("shuffle returns a vector of %d elements, vectors can only have 2, 4, 8, 16, 32 or 64", len(indexes)), call.Token)
+		return ctypes.TODO()
+	}
+
+	for _, index := range indexes {
+		integer, isInteger := index.(*ast.Integer)
+		if !isInteger || integer.Value < 0 || integer.Value >= 2*vector.Length {
+			s.builtinError(fmt.Sprintf("shuffle indexes must be integer literals between 0 and %d, got: %s", 2*vector.Length-1, index), call.Token)
+			return ctypes.TODO()
+		}
+
+		s.analyzeExpression(index)
+	}
+
+	return &ctypes.Vector{Inner: vector.Inner, Length: int64(len(indexes))}
+}
+
+// reductions that each kind of vector accepts, they are written as identifiers: @reduce(v, add)
+var vectorReductions = map[string]string{
+	"add": "numbers",
+	"mul": "numbers",
+	"min": "numbers",
+	"max": "numbers",
+	"and": "integers",
+	"or":  "integers",
+	"xor": "integers",
+}
+
+// analyzeReduce analyzes @reduce(vector, operation), which combines every element of the vector
+// with the operation.
+func (s *Semantic) analyzeReduce(call *ast.BuiltinCall) ctypes.Type {
+	s.throwInvalidOperationForConstant("you can't use vectors on constants", call)
+	vector := s.analyzeVector(call, call.Parameters[0])
+	if vector == nil {
+		return ctypes.TODO()
+	}
+
+	identifier, isIdentifier := call.Parameters[1].(*ast.Identifier)
+	if !isIdentifier {
+		s.builtinError("expected one of add, mul, min, max, and, or, xor on reduce, got: "+call.Parameters[1].String(), call.Token)
+		return ctypes.TODO()
+	}
+
+	operation := identifier.Token.Literal
+	accepts, ok := vectorReductions[operation]
+	if !ok ||
+		(accepts == "numbers" && vector.Inner == ctypes.I1) ||
+		(accepts == "integers" && ctypes.IsFloat(vector.Inner)) {
+		s.builtinError("can't reduce a "+vector.String()+" with "+operation, call.Token)
+		return ctypes.TODO()
+	}
+
+	return vector.Inner
+}
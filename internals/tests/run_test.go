@@ -25,6 +25,7 @@ func TestSrcs(t *testing.T) {
 		"for_statement2.cd":     "1 2 3 4 5 6 7 8 9 10",
 		"string_literal.cd":     "Hello world!",
 		"unsigned_ints.cd":      "4294967200 -96",
+		"unsigned_casts.cd":     "200 200 65000 65000 -56",
 		"linked_list.cd":        "100",
 		"fibonacci.cd":          "75025",
 		"nested_loops.cd":       "0 0 1 2 3 4 5 6 7 8 9 10 1 1 2 3 4 5 6 7 8 9 10 2 2 3 4 5 6 7 8 9 10 3 3 4 5 6 7 8 9 10 4 4 5 6 7 8 9 10 5 5 6 7 8 9 10 6 6 7 8 9 10 7 7 8 9 10 8 8 9 10 9 9 10 10 10 11 12 13 14 15 16 17 18 19 20 21 22 23 24 25 26 27 28 29 30 31 32 33 34 35 36 37 38 39 40 41 42 43 44 45 46 47 48 49 50 51 52 53 54 55 56 57 58 59 60 61 62 63 64 65 66 67 68 69 70 71 72 73 74 75 76 77 78 79 80 81 82 83 84 85 86 87 88 89 90 91 92 93 94 95 96 97 98 99 100",
@@ -36,6 +37,12 @@ func TestSrcs(t *testing.T) {
 		"print.cd":              "Thing",
 		"macro_if.cd":           fmt.Sprintf("%s %s", runtime.GOOS, runtime.GOARCH),
 		"1variadic.cd":          "formatting a number: 3",
+		"hashmap_churn.cd":      "16 2 3",
+		"std_strings.cd":        "1 0 21 count: 19 spaced out 1 7 HéLLO wÖrld -42 1 0",
+		"std_os.cd":             "0 0 13 1 one two three 2 0 3 1 set 1 0",
+		"std_allocator.cd":      "100 5050 1 1 1 1 1 1 1 15",
+		"std_sort.cd":           "0 1 -1 0 0 4 0 97",
+		"std_thread.cd":         "4000 60 0",
 		"0arr.cd":               "1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1",
 	}
 
@@ -63,6 +70,7 @@ func TestSrcs(t *testing.T) {
 		}
 		semantic.ResetPaths()
 		s.ContextDirectoryPath = "./src"
+		s.StdDirectory = "../../std"
 		s.Analyze(root)
 
 		if len(s.Errors) > 0 {
@@ -95,6 +103,7 @@ func BenchmarkSrcs(t *testing.B) {
 		"for_statement2.cd":     "1 2 3 4 5 6 7 8 9 10",
 		"string_literal.cd":     "Hello world!",
 		"unsigned_ints.cd":      "4294967200 -96",
+		"unsigned_casts.cd":     "200 200 65000 65000 -56",
 		"linked_list.cd":        "100",
 		"fibonacci.cd":          "75025",
 		"nested_loops.cd":       "0 0 1 2 3 4 5 6 7 8 9 10 1 1 2 3 4 5 6 7 8 9 10 2 2 3 4 5 6 7 8 9 10 3 3 4 5 6 7 8 9 10 4 4 5 6 7 8 9 10 5 5 6 7 8 9 10 6 6 7 8 9 10 7 7 8 9 10 8 8 9 10 9 9 10 10 10 11 12 13 14 15 16 17 18 19 20 21 22 23 24 25 26 27 28 29 30 31 32 33 34 35 36 37 38 39 40 41 42 43 44 45 46 47 48 49 50 51 52 53 54 55 56 57 58 59 60 61 62 63 64 65 66 67 68 69 70 71 72 73 74 75 76 77 78 79 80 81 82 83 84 85 86 87 88 89 90 91 92 93 94 95 96 97 98 99 100",
@@ -106,6 +115,12 @@ func BenchmarkSrcs(t *testing.B) {
 		"print.cd":              "Thing",
 		"macro_if.cd":           fmt.Sprintf("%s %s", runtime.GOOS, runtime.GOARCH),
 		"1variadic.cd":          "formatting a number: 3",
+		"hashmap_churn.cd":      "16 2 3",
+		"std_strings.cd":        "1 0 21 count: 19 spaced out 1 7 HéLLO wÖrld -42 1 0",
+		"std_os.cd":             "0 0 13 1 one two three 2 0 3 1 set 1 0",
+		"std_allocator.cd":      "100 5050 1 1 1 1 1 1 1 15",
+		"std_sort.cd":           "0 1 -1 0 0 4 0 97",
+		"std_thread.cd":         "4000 60 0",
 		"0arr.cd":               "1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1",
 	}
 
@@ -132,6 +147,7 @@ func BenchmarkSrcs(t *testing.B) {
 		}
 		semantic.ResetPaths()
 		s.ContextDirectoryPath = "./src"
+		s.StdDirectory = "../../std"
 		s.Analyze(root)
 
 		if len(s.Errors) > 0 {
@@ -0,0 +1,14 @@
+package token
+
+// Comment is a comment of the source code, comments aren't tokens so the lexer keeps them on a side table
+// that tools like formatters and documentation generators can use.
+type Comment struct {
+	// Literal is the comment like it's written, including the // or the /* */
+	Literal  string
+	Line     uint32
+	Position uint32
+	// OverallPosition is the offset of the comment on the input
+	OverallPosition int
+	// Doc is true for /// comments, they document the declaration that follows them
+	Doc bool
+}
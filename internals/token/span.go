@@ -0,0 +1,177 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FileID identifies the source file of a token, 0 is an unknown file
+type FileID uint32
+
+type file struct {
+	name   string
+	source string
+}
+
+var (
+	filesMutex sync.RWMutex
+	files      = []file{{}}
+	fileIDs    = map[string]FileID{}
+)
+
+// AddFile registers a source file so tokens can point to it, adding a file
+// with the same name again replaces its source and keeps its id
+func AddFile(name, source string) FileID {
+	filesMutex.Lock()
+	defer filesMutex.Unlock()
+	if id, ok := fileIDs[name]; ok {
+		files[id].source = source
+		return id
+	}
+
+	id := FileID(len(files))
+	files = append(files, file{name: name, source: source})
+	fileIDs[name] = id
+	return id
+}
+
+// Name returns the name of the file, it's empty if the file is unknown
+func (f FileID) Name() string {
+	filesMutex.RLock()
+	defer filesMutex.RUnlock()
+	if int(f) >= len(files) {
+		return ""
+	}
+
+	return files[f].name
+}
+
+// Source returns the contents of the file
+func (f FileID) Source() string {
+	filesMutex.RLock()
+	defer filesMutex.RUnlock()
+	if int(f) >= len(files) {
+		return ""
+	}
+
+	return files[f].source
+}
+
+// Span is the range of the source that a token or a node covers. Lines and positions start at 1
+// and the end position is the column right after the last character.
+type Span struct {
+	File        FileID
+	Line        uint32
+	Position    uint32
+	EndLine     uint32
+	EndPosition uint32
+}
+
+// Span returns the range of the token
+func (t Token) Span() Span {
+	return Span{File: t.File, Line: t.Line, Position: t.Position, EndLine: t.EndLine, EndPosition: t.EndPosition}
+}
+
+// IsZero returns true if the span doesn't point anywhere, like the ones of nodes that the compiler creates
+func (s Span) IsZero() bool {
+	return s.Line == 0
+}
+
+// To returns the span that goes from the start of s to the end of end
+func (s Span) To(end Span) Span {
+	if s.IsZero() {
+		return end
+	}
+
+	if end.IsZero() {
+		return s
+	}
+
+	s.EndLine, s.EndPosition = end.EndLine, end.EndPosition
+	return s
+}
+
+// String returns the location of the start of the span as file:line:position
+func (s Span) String() string {
+	if name := s.File.Name(); name != "" {
+		return fmt.Sprintf("%s:%d:%d", name, s.Line, s.Position)
+	}
+
+	return fmt.Sprintf("%d:%d", s.Line, s.Position)
+}
+
+// Source returns the text of the source that the span covers, it's empty if the source of the file isn't known
+func (s Span) Source() string {
+	source := s.File.Source()
+	if source == "" || s.IsZero() {
+		return ""
+	}
+
+	lines := strings.SplitAfter(source, "\n")
+	if int(s.EndLine) > len(lines) || s.EndLine < s.Line {
+		return ""
+	}
+
+	start := offset(lines, s.Line, s.Position)
+	end := offset(lines, s.EndLine, s.EndPosition)
+	if start < 0 || end < start || end > len(source) {
+		return ""
+	}
+
+	return source[start:end]
+}
+
+// offset returns the index in the source of the position of the line, -1 if the line doesn't have it
+func offset(lines []string, line, position uint32) int {
+	index := 0
+	for _, l := range lines[:line-1] {
+		index += len(l)
+	}
+
+	column := int(position) - 1
+	if column < 0 || column > len(lines[line-1]) {
+		return -1
+	}
+
+	return index + column
+}
+
+// Underline returns the first line of the span in source and a line below it that
+// underlines the span with ^, if the span has more lines it's underlined until the end of the line
+func (s Span) Underline(source string) string {
+	lines := strings.Split(source, "\n")
+	if s.IsZero() || int(s.Line) > len(lines) {
+		return ""
+	}
+
+	line := strings.TrimRight(lines[s.Line-1], "\r")
+	start := int(s.Position) - 1
+	if start < 0 {
+		start = 0
+	}
+
+	if start > len(line) {
+		start = len(line)
+	}
+
+	end := int(s.EndPosition) - 1
+	if s.EndLine != s.Line || end > len(line) {
+		end = len(line)
+	}
+
+	if end <= start {
+		end = start + 1
+	}
+
+	// keep the tabs so the marks are aligned with the line
+	padding := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+
+		return ' '
+	}, line[:start])
+
+	return fmt.Sprintf("%s\n%s%s", line, padding, strings.Repeat("^", end-start))
+}
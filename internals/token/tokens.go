@@ -11,6 +11,10 @@ type Token struct {
 	// This is only for retrieving error messages, maybe we shouldn't increase 4 bytes our tokens because of
 	// that.
 	OverallPosition int
+	// EndLine and EndPosition are where the token ends, EndPosition is the column after its last character
+	EndLine     uint32
+	EndPosition uint32
+	File        FileID
 }
 
 const (
@@ -23,6 +27,7 @@ const (
 	FLOAT  = TypeToken("FLOAT")  // 1.0
 	HEX    = TypeToken("HEX")    // 0x12191
 	BINARY = TypeToken("BINARY") // 0b1101010
+	OCTAL  = TypeToken("OCTAL")  // 0o17
 	STRING = TypeToken("STRING")
 
 	// Operators
@@ -65,29 +70,32 @@ const (
 	ASSIGN    = TypeToken("=")
 
 	// Keywords
-	TYPE     = TypeToken("type")
-	PUBLIC   = TypeToken("pub")
-	UNION    = TypeToken("union")
-	STRUCT   = TypeToken("STRUCT")
-	FUNCTION = TypeToken("FUNCTION")
-	TRUE     = TypeToken("TRUE")
-	FALSE    = TypeToken("FALSE")
-	IF       = TypeToken("IF")
-	ELSE     = TypeToken("ELSE")
-	RETURN   = TypeToken("RETURN")
-	IMPORT   = TypeToken("IMPORT")
-	FOR      = TypeToken("FOR")
-	BREAK    = TypeToken("BREAK")
-	CONTINUE = TypeToken("CONTINUE")
-	EXTERN   = TypeToken("EXTERN")
-	AS       = TypeToken("AS")
-	MACRO_IF = TypeToken("#IF")
+	TYPE       = TypeToken("type")
+	PUBLIC     = TypeToken("pub")
+	UNION      = TypeToken("union")
+	STRUCT     = TypeToken("STRUCT")
+	FUNCTION   = TypeToken("FUNCTION")
+	TRUE       = TypeToken("TRUE")
+	FALSE      = TypeToken("FALSE")
+	IF         = TypeToken("IF")
+	ELSE       = TypeToken("ELSE")
+	RETURN     = TypeToken("RETURN")
+	IMPORT     = TypeToken("IMPORT")
+	FOR        = TypeToken("FOR")
+	BREAK      = TypeToken("BREAK")
+	CONTINUE   = TypeToken("CONTINUE")
+	EXTERN     = TypeToken("EXTERN")
+	AS         = TypeToken("AS")
+	MACRO_IF   = TypeToken("#IF")
+	MACRO_ELIF = TypeToken("#ELIF")
+	MACRO_ELSE = TypeToken("#ELSE")
 
-	SWITCH  = TypeToken("SWITCH")
-	CASE    = TypeToken("CASE")
-	DEFAULT = TypeToken("DEFAULT")
-	CONST   = TypeToken("CONSTANT")
-	CHAR    = TypeToken("CHAR")
+	SWITCH   = TypeToken("SWITCH")
+	CASE     = TypeToken("CASE")
+	DEFAULT  = TypeToken("DEFAULT")
+	CONST    = TypeToken("CONSTANT")
+	CHAR     = TypeToken("CHAR")
+	COMPTIME = TypeToken("COMPTIME")
 )
 
 var keywords = map[string]TypeToken{
@@ -111,6 +119,7 @@ var keywords = map[string]TypeToken{
 	"case":     CASE,
 	"default":  DEFAULT,
 	"const":    CONST,
+	"comptime": COMPTIME,
 }
 
 // LookupIdent Looks up in the keywords table if its a keyword, if its not it will return IDENT as a TypeToken
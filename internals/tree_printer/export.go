@@ -0,0 +1,376 @@
+package tree_printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gabivlj/candice/internals/ast"
+	"github.com/gabivlj/candice/internals/ctypes"
+	"github.com/gabivlj/candice/internals/token"
+)
+
+// Format is the output format of the tree
+type Format string
+
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	Dot  Format = "dot"
+)
+
+// ParseFormat returns the format with the passed name, an empty name is Text
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", Text:
+		return Text, nil
+	case JSON, Dot:
+		return Format(name), nil
+	}
+
+	return "", fmt.Errorf("unknown tree format '%s', use text, json or dot", name)
+}
+
+// Module is a parsed file of a project
+type Module struct {
+	Path    string
+	ID      string
+	Program *ast.Program
+}
+
+// Node is a node of the tree that can be exported. Identifiers don't have the id of their module, so
+// the output of a module is the same if its source doesn't change.
+type Node struct {
+	Kind string `json:"kind"`
+	Span *Span  `json:"span,omitempty"`
+	// Type is the type of the expression, it's only known when the tree has been analyzed
+	Type     string         `json:"type,omitempty"`
+	Fields   map[string]any `json:"fields,omitempty"`
+	Children []Child        `json:"children,omitempty"`
+}
+
+// Child is a node that is inside another one, Field is where the parent keeps it, like Left or Parameters[1]
+type Child struct {
+	Field string `json:"field"`
+	Node  *Node  `json:"node"`
+}
+
+// Span is the range of the source that a node covers, lines and columns start at 1
+type Span struct {
+	File      string `json:"file,omitempty"`
+	Line      uint32 `json:"line"`
+	Column    uint32 `json:"column"`
+	EndLine   uint32 `json:"endLine"`
+	EndColumn uint32 `json:"endColumn"`
+}
+
+type exportedModule struct {
+	Path    string `json:"path"`
+	ID      string `json:"id"`
+	Program *Node  `json:"program"`
+}
+
+// WriteModules writes the tree of every module in the passed format
+func WriteModules(writer io.Writer, format Format, modules []Module) error {
+	switch format {
+	case JSON:
+		exported := make([]exportedModule, 0, len(modules))
+		for _, module := range modules {
+			exported = append(exported, exportedModule{Path: module.Path, ID: module.ID, Program: Export(module.Program)})
+		}
+
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Modules []exportedModule `json:"modules"`
+		}{exported})
+	case Dot:
+		return writeDot(writer, modules)
+	}
+
+	var s []string
+	for _, module := range modules {
+		s = append(s, module.Path+"\n"+ProcessProgram(module.Program))
+	}
+
+	_, err := io.WriteString(writer, strings.Join(s, "\n\n")+"\n")
+	return err
+}
+
+// Export converts the tree of node so it can be encoded
+func Export(node ast.Node) *Node {
+	exported := &Node{
+		Kind:   strings.TrimPrefix(reflect.TypeOf(node).String(), "*ast."),
+		Span:   exportSpan(node.Span()),
+		Fields: fields(node),
+	}
+
+	if expression, ok := node.(ast.Expression); ok {
+		exported.Type = expressionType(expression)
+	}
+
+	labels := childLabels(node)
+	for _, child := range ast.Children(node) {
+		exported.Children = append(exported.Children, Child{Field: labels[child], Node: Export(child)})
+	}
+
+	return exported
+}
+
+func exportSpan(span token.Span) *Span {
+	if span.IsZero() {
+		return nil
+	}
+
+	return &Span{
+		File:      span.File.Name(),
+		Line:      span.Line,
+		Column:    span.Position,
+		EndLine:   span.EndLine,
+		EndColumn: span.EndPosition,
+	}
+}
+
+// expressionType returns the type of the expression, expressions that the
+// compiler creates might not have a node to hold it
+func expressionType(expression ast.Expression) string {
+	value := reflect.ValueOf(expression).Elem()
+	if field := value.FieldByName("Node"); field.IsValid() && field.Kind() == reflect.Ptr && field.IsNil() {
+		return ""
+	}
+
+	return typeString(expression.GetType())
+}
+
+func typeString(t ctypes.Type) string {
+	if t == nil || reflect.ValueOf(t).IsNil() {
+		return ""
+	}
+
+	if function, ok := t.(*ctypes.Function); ok {
+		return strings.TrimSpace(function.FullString())
+	}
+
+	return t.String()
+}
+
+func typeStrings(types []ctypes.Type) []string {
+	result := make([]string, 0, len(types))
+	for _, t := range types {
+		result = append(result, typeString(t))
+	}
+
+	return result
+}
+
+func names(identifiers []string) []string {
+	names := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		names = append(names, ast.RetrieveID(identifier))
+	}
+
+	return names
+}
+
+func structFields(fieldNames []string, types []ctypes.Type) []string {
+	fields := make([]string, 0, len(types))
+	for i, t := range types {
+		fields = append(fields, ast.RetrieveID(fieldNames[i])+" "+typeString(t))
+	}
+
+	return fields
+}
+
+// fields returns the values of the node that aren't other nodes, empty values are left out
+func fields(node ast.Node) map[string]any {
+	values := map[string]any{}
+	add := func(name string, value any) {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return
+			}
+		case []string:
+			if len(v) == 0 {
+				return
+			}
+		case bool:
+			if !v {
+				return
+			}
+		}
+
+		values[name] = value
+	}
+
+	switch n := node.(type) {
+	case *ast.Identifier:
+		add("name", ast.RetrieveID(n.Name))
+	case *ast.BinaryOperation:
+		add("operation", n.Operation.String())
+	case *ast.PrefixOperation:
+		add("operation", n.Operation.String())
+	case *ast.BuiltinCall:
+		add("name", n.Name)
+		add("typeParameters", typeStrings(n.TypeParameters))
+	case *ast.Integer:
+		add("value", n.Value)
+	case *ast.Float:
+		add("value", n.Value)
+	case *ast.StringLiteral:
+		values["value"] = n.Value
+	case *ast.StructLiteral:
+		add("name", ast.RetrieveID(n.Name))
+		add("module", ast.RetrieveID(n.Module))
+	case *ast.AnonymousFunction:
+		add("function", typeString(n.FunctionType))
+		add("capturedVariables", names(n.CapturedVariables))
+	case *ast.CommaExpressions:
+		add("isAssignment", n.IsAssignment)
+	case *ast.DeclarationStatement:
+		add("name", ast.RetrieveID(n.Name))
+		add("declaredType", typeString(n.Type))
+		add("constant", n.Constant)
+	case *ast.MultipleDeclarationStatement:
+		add("names", names(n.Names))
+		add("declaredType", typeString(n.Type))
+		add("constant", n.Constant)
+	case *ast.StructStatement:
+		add("name", typeString(n.Type))
+		add("fields", structFields(n.Type.Names, n.Type.Fields))
+		add("doc", n.Doc)
+	case *ast.UnionStatement:
+		add("name", typeString(n.Type))
+		add("fields", structFields(n.Type.Names, n.Type.Fields))
+		add("doc", n.Doc)
+	case *ast.FunctionDeclarationStatement:
+		add("function", typeString(n.FunctionType))
+		add("doc", n.Doc)
+	case *ast.ExternStatement:
+		add("declaredType", typeString(n.Type))
+	case *ast.ReturnStatement:
+		add("returnType", typeString(n.Type))
+	case *ast.ImportStatement:
+		add("name", ast.RetrieveID(n.Name))
+		add("typeParameters", typeStrings(n.Types))
+	case *ast.GenericTypeDefinition:
+		add("name", ast.RetrieveID(n.Name))
+		add("replacedType", typeString(n.ReplacedType))
+	case *ast.TypeDefinition:
+		add("name", ast.RetrieveID(n.Name))
+		add("declaredType", typeString(n.Type))
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	return values
+}
+
+var nodeInterface = reflect.TypeOf((*ast.Node)(nil)).Elem()
+
+// childLabels returns the name of the field of node that holds each one of its children
+func childLabels(node ast.Node) map[ast.Node]string {
+	labels := map[ast.Node]string{}
+	var label func(value reflect.Value, name string)
+	label = func(value reflect.Value, name string) {
+		switch value.Kind() {
+		case reflect.Interface, reflect.Ptr:
+			if value.IsNil() || !value.Type().Implements(nodeInterface) {
+				return
+			}
+
+			if child := value.Interface().(ast.Node); labels[child] == "" {
+				labels[child] = name
+			}
+		case reflect.Slice:
+			for i := 0; i < value.Len(); i++ {
+				label(value.Index(i), fmt.Sprintf("%s[%d]", name, i))
+			}
+		case reflect.Struct:
+			// struct literal values
+			for i := 0; i < value.NumField(); i++ {
+				label(value.Field(i), name+"."+value.Type().Field(i).Name)
+			}
+		}
+	}
+
+	value := reflect.ValueOf(node).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() || field.Type.Kind() == reflect.Struct {
+			continue
+		}
+
+		label(value.Field(i), field.Name)
+	}
+
+	return labels
+}
+
+// writeDot writes the modules as a Graphviz graph, each module is a cluster of the graph
+func writeDot(writer io.Writer, modules []Module) error {
+	builder := strings.Builder{}
+	builder.WriteString("digraph AST {\n")
+	builder.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	id := 0
+	var write func(node *Node) int
+	write = func(node *Node) int {
+		current := id
+		id++
+		label := []string{node.Kind}
+		keys := make([]string, 0, len(node.Fields))
+		for key := range node.Fields {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := node.Fields[key]
+			if list, ok := value.([]string); ok {
+				value = strings.Join(list, ", ")
+			}
+
+			label = append(label, fmt.Sprintf("%s: %v", key, value))
+		}
+
+		if node.Type != "" {
+			label = append(label, "type: "+node.Type)
+		}
+
+		if node.Span != nil {
+			label = append(label, fmt.Sprintf("%d:%d", node.Span.Line, node.Span.Column))
+		}
+
+		fmt.Fprintf(&builder, "\t\tn%d [label=%s];\n", current, dotString(strings.Join(label, "\n")))
+		for _, child := range node.Children {
+			childID := write(child.Node)
+			fmt.Fprintf(&builder, "\t\tn%d -> n%d [label=%s];\n", current, childID, dotString(child.Field))
+		}
+
+		return current
+	}
+
+	for i, module := range modules {
+		fmt.Fprintf(&builder, "\tsubgraph cluster_%d {\n", i)
+		fmt.Fprintf(&builder, "\t\tlabel=%s;\n", dotString(module.Path))
+		write(Export(module.Program))
+		builder.WriteString("\t}\n")
+	}
+
+	builder.WriteString("}\n")
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// dotString quotes s as a Graphviz string
+func dotString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
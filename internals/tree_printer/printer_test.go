@@ -1,6 +1,8 @@
 package tree_printer
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/gabivlj/candice/internals/lexer"
@@ -48,3 +50,59 @@ func TestAnonymousFunction(t *testing.T) {
                                               ╠types.Return═i32
                                               ╚`)
 }
+
+func TestExport(t *testing.T) {
+	p := parser.New(lexer.NewFile("export.cd", "func main() {\n\tx := add(1, 2.5);\n}"))
+	program := p.Parse()
+	a.Assert(len(p.Errors) == 0, p.Errors)
+
+	builder := strings.Builder{}
+	a.AssertErr(WriteModules(&builder, JSON, []Module{{Path: "export.cd", ID: p.ID, Program: program}}))
+	var output struct {
+		Modules []struct {
+			Path    string
+			Program Node
+		}
+	}
+
+	a.AssertErr(json.Unmarshal([]byte(builder.String()), &output))
+	a.Assert(len(output.Modules) == 1)
+	a.AssertEqual(output.Modules[0].Path, "export.cd")
+
+	function := output.Modules[0].Program.Children[0]
+	a.AssertEqual(function.Field, "Statements[0]")
+	a.AssertEqual(function.Node.Kind, "FunctionDeclarationStatement")
+	a.AssertEqual(function.Node.Fields["function"].(string), "func main()")
+
+	declaration := function.Node.Children[0].Node.Children[0].Node
+	a.AssertEqual(declaration.Kind, "DeclarationStatement")
+	a.AssertEqual(declaration.Fields["name"].(string), "x")
+	a.Assert(*declaration.Span == Span{File: "export.cd", Line: 2, Column: 2, EndLine: 2, EndColumn: 19}, declaration.Span)
+
+	call := declaration.Children[0].Node
+	a.AssertEqual(call.Kind, "Call")
+	a.AssertEqual(call.Children[0].Field, "Left")
+	a.AssertEqual(call.Children[0].Node.Fields["name"].(string), "add")
+	a.AssertEqual(call.Children[2].Field, "Parameters[1]")
+	a.AssertEqual(call.Children[2].Node.Kind, "Float")
+	a.AssertEqual(call.Children[2].Node.Type, "f32")
+}
+
+func TestWriteDot(t *testing.T) {
+	p := parser.New(lexer.New(`a := "say \"hi\"";`))
+	program := p.Parse()
+	builder := strings.Builder{}
+	a.AssertErr(WriteModules(&builder, Dot, []Module{{Path: "dot.cd", Program: program}}))
+	a.AssertEqual(builder.String(), `digraph AST {
+	node [shape=box, fontname="monospace"];
+	subgraph cluster_0 {
+		label="dot.cd";
+		n0 [label="Program\n1:1"];
+		n1 [label="DeclarationStatement\nname: a\n1:1"];
+		n2 [label="StringLiteral\nvalue: say \"hi\"\ntype: *i8\n1:6"];
+		n1 -> n2 [label="Expression"];
+		n0 -> n1 [label="Statements[0]"];
+	}
+}
+`)
+}
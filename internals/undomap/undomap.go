@@ -32,6 +32,16 @@ func (u *UndoMap[K, T]) Get(key K) T {
 	}
 }
 
+// Outermost returns the first value that was added with the key and hasn't been popped
+func (u *UndoMap[K, T]) Outermost(key K) T {
+	var t T
+	if values := u.values[key]; len(values) > 0 {
+		return values[0]
+	}
+
+	return t
+}
+
 func (u *UndoMap[K, T]) Pop() (K, T) {
 	key := u.stack[len(u.stack)-1]
 	u.stack = u.stack[:len(u.stack)-1]
@@ -0,0 +1,235 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Severity of a diagnostic
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is an error or a warning in a form that tools can read. Lines and columns start at 1
+// and the end column is the one right after the span, they are 0 when the location is unknown.
+type Diagnostic struct {
+	Severity  Severity `json:"severity"`
+	Code      string   `json:"code"`
+	Message   string   `json:"message"`
+	File      string   `json:"file,omitempty"`
+	Line      uint32   `json:"line,omitempty"`
+	Column    uint32   `json:"column,omitempty"`
+	EndLine   uint32   `json:"endLine,omitempty"`
+	EndColumn uint32   `json:"endColumn,omitempty"`
+	Hints     []string `json:"hints,omitempty"`
+}
+
+// Diagnosable is implemented by errors that know where they happened
+type Diagnosable interface {
+	Diagnostic() Diagnostic
+}
+
+// Format is how diagnostics are written
+type Format string
+
+const (
+	// Text writes every diagnostic to the terminal as soon as it happens
+	Text Format = "text"
+	// JSON and SARIF collect the diagnostics and write them to stderr on Flush
+	JSON  Format = "json"
+	SARIF Format = "sarif"
+)
+
+var (
+	format  Format    = Text
+	output  io.Writer = os.Stderr
+	pending []Diagnostic
+	flushed bool
+)
+
+// SetFormat changes how diagnostics are written, an empty name is Text
+func SetFormat(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	switch Format(name) {
+	case "", Text:
+		format = Text
+	case JSON, SARIF:
+		format = Format(name)
+	default:
+		return fmt.Errorf("unknown diagnostics format '%s', use text, json or sarif", name)
+	}
+
+	return nil
+}
+
+// collect stores the diagnostic until the next Flush, it returns false on Text format
+// so the caller writes it to the terminal instead
+func collect(diagnostic Diagnostic) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if format == Text {
+		return false
+	}
+
+	pending = append(pending, diagnostic)
+	return true
+}
+
+// Flush writes the diagnostics that were collected since the last Flush as a single document.
+// The first Flush always writes one so tools get an empty list when everything went fine.
+func Flush() {
+	mu.Lock()
+	defer mu.Unlock()
+	if format == Text || flushed && len(pending) == 0 {
+		return
+	}
+
+	diagnostics := pending
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+
+	var document any = struct {
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}{diagnostics}
+
+	if format == SARIF {
+		document = sarif(diagnostics)
+	}
+
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(document)
+	pending = nil
+	flushed = true
+}
+
+// code turns the kind of an error like "Internally At Compile Time" into internally-at-compile-time
+func code(kind string) string {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if kind == "" {
+		return "error"
+	}
+
+	return strings.Join(strings.Fields(kind), "-")
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   uint32 `json:"startLine"`
+	StartColumn uint32 `json:"startColumn,omitempty"`
+	EndLine     uint32 `json:"endLine,omitempty"`
+	EndColumn   uint32 `json:"endColumn,omitempty"`
+}
+
+// sarif converts the diagnostics to a SARIF 2.1.0 log, the hints are added to the message
+func sarif(diagnostics []Diagnostic) sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "candice",
+			InformationURI: "https://github.com/gabivlj/candice",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := map[string]bool{}
+	for _, diagnostic := range diagnostics {
+		if !rules[diagnostic.Code] {
+			rules[diagnostic.Code] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: diagnostic.Code})
+		}
+
+		message := diagnostic.Message
+		for _, hint := range diagnostic.Hints {
+			message += "\nHint: " + hint
+		}
+
+		result := sarifResult{
+			RuleID:  diagnostic.Code,
+			Level:   string(diagnostic.Severity),
+			Message: sarifMessage{Text: message},
+		}
+
+		if diagnostic.File != "" {
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(diagnostic.File)},
+			}}
+
+			if diagnostic.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{
+					StartLine:   diagnostic.Line,
+					StartColumn: diagnostic.Column,
+					EndLine:     diagnostic.EndLine,
+					EndColumn:   diagnostic.EndColumn,
+				}
+			}
+
+			result.Locations = []sarifLocation{location}
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
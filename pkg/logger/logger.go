@@ -2,21 +2,41 @@ package logger
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/lucasb-eyer/go-colorful"
 	"github.com/muesli/termenv"
 )
 
-var profile = termenv.TrueColor
-var redForeground = profile.FromColor(colorful.LinearRgb(1, 0.5, 0.5))
+// profile has no colors when NO_COLOR is set or the output isn't a terminal
+var profile = termenv.EnvColorProfile()
+var redForeground = color(colorful.LinearRgb(1, 0.5, 0.5))
 
 //245, 166, 132
-var orangeFg = profile.FromColor(colorful.LinearRgb(245.0/255, 166.0/255, 132.0/255))
-var redBg = profile.FromColor(colorful.LinearRgb(224.0/255, 20.0/255, 68.0/255))
-var orange = profile.FromColor(colorful.LinearRgb(0.9, 0.3, 0.0))
-var white = profile.FromColor(termenv.ConvertToRGB(termenv.ANSIBrightWhite))
+var orangeFg = color(colorful.LinearRgb(245.0/255, 166.0/255, 132.0/255))
+var redBg = color(colorful.LinearRgb(224.0/255, 20.0/255, 68.0/255))
+var orange = color(colorful.LinearRgb(0.9, 0.3, 0.0))
+var white = color(termenv.ConvertToRGB(termenv.ANSIBrightWhite))
+var green = color(termenv.ConvertToRGB(termenv.ANSIGreen))
+
+// color converts c to the profile, it's nil on the Ascii profile so styles don't write escape codes
+func color(c colorful.Color) termenv.Color {
+	if profile == termenv.Ascii {
+		return nil
+	}
+
+	return profile.FromColor(c)
+}
+
+// mu guards the diagnostics, modules are analyzed concurrently and they can log warnings
+var mu sync.Mutex
 
 func Warning(text string) {
+	if collect(Diagnostic{Severity: SeverityWarning, Code: "warning", Message: strings.TrimSpace(text)}) {
+		return
+	}
+
 	s := termenv.String(" Warning ")
 	//#ffa442
 	s = s.
@@ -32,6 +52,15 @@ func WarningNoTag(text string) {
 }
 
 func Error(kind, text string, paragraph ...interface{}) {
+	message := strings.TrimSpace(text)
+	if len(paragraph) > 0 {
+		message = strings.TrimSpace(message + "\n" + fmt.Sprintln(paragraph...))
+	}
+
+	if collect(Diagnostic{Severity: SeverityError, Code: code(kind), Message: message}) {
+		return
+	}
+
 	s := termenv.String(" Error " + kind + " ")
 	//#ffa442
 	s = s.
@@ -42,9 +71,39 @@ func Error(kind, text string, paragraph ...interface{}) {
 	fmt.Println(paragraph...)
 }
 
+// ReportError logs the error with the kind of the step that failed, errors that implement
+// Diagnosable keep their location and hints on the json and sarif formats
+func ReportError(kind string, err error) {
+	if !report(SeverityError, kind, err) {
+		Error(kind, err.Error())
+	}
+}
+
+// ReportWarning logs the warning, like ReportError
+func ReportWarning(err error) {
+	if !report(SeverityWarning, "warning", err) {
+		Warning(err.Error())
+	}
+}
+
+func report(severity Severity, kind string, err error) bool {
+	diagnosable, ok := err.(Diagnosable)
+	if !ok {
+		return false
+	}
+
+	diagnostic := diagnosable.Diagnostic()
+	diagnostic.Severity = severity
+	if diagnostic.Code == "" {
+		diagnostic.Code = code(kind)
+	}
+
+	return collect(diagnostic)
+}
+
 func Success(text string) {
 	s := termenv.String(text)
 	s = s.
-		Foreground(termenv.ANSIGreen)
+		Foreground(green)
 	fmt.Println(s)
 }
@@ -2,6 +2,7 @@ package random
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 	"unsafe"
 )
@@ -15,10 +16,15 @@ const (
 
 var src = rand.NewSource(time.Now().UnixNano())
 
+// rand.Source is not safe for concurrent use, modules can be compiled in parallel
+var srcMutex sync.Mutex
+
 // RandomString is the fastest implementation of random string that I've found on this language
 // https://stackoverflow.com/questions/22892120/how-to-generate-a-random-string-of-a-fixed-length-in-go
 func RandomString(n int) string {
 	b := make([]byte, n)
+	srcMutex.Lock()
+	defer srcMutex.Unlock()
 	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
 	for i, cache, remain := n-1, src.Int63(), letterIdxMax; i >= 0; {
 		if remain == 0 {
@@ -0,0 +1,10 @@
+// Package std embeds the candice standard library, so the compiler binary can be
+// distributed without it.
+package std
+
+import "embed"
+
+// Files contains every module of the standard library.
+//
+//go:embed *.cd
+var Files embed.FS